@@ -0,0 +1,65 @@
+package actions
+
+import (
+	"fmt"
+	"log"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/distgit"
+)
+
+// FindAdoptions returns the packages that were orphaned in prev and are
+// neither orphaned in cur nor retired along with their new point of contact.
+// Packages in retired are known to be retired without checking distgit.
+// Packages that pagure_poc.json still lists as orphaned are skipped.
+func FindAdoptions(
+	prev, cur *common.Orphans,
+	e *distgit.ExtrasClient,
+	retired []string,
+) ([]common.Adoption, error) {
+	if cur.FinishedAt == nil {
+		return nil, fmt.Errorf("finished_at was not included in the orphans data")
+	}
+	retiredset := mapset.NewThreadUnsafeSet(retired...)
+	d, err := common.DiffOrphans(prev, cur, common.OrphansDiffOptions{
+		IsRetired: func(pkg string) (bool, error) {
+			if retiredset.Contains(pkg) {
+				return true, nil
+			}
+			return e.IsRetired(pkg, "rawhide")
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	adoptions := []common.Adoption{}
+	if len(d.Adopted) == 0 {
+		return adoptions, nil
+	}
+	pocs, err := e.GetPagurePOC()
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range d.Adopted {
+		poc, ok := pocs.RPMS[pkg]
+		admin := poc.Fedora
+		if admin == "" {
+			admin = poc.Admin
+		}
+		if !ok || admin == "" || admin == common.OrphanUID {
+			log.Printf("skipping %s: no new point of contact in pagure_poc", pkg)
+			continue
+		}
+		a := common.Adoption{
+			Package:    pkg,
+			Admin:      admin,
+			DetectedAt: cur.FinishedAt.UTC(),
+		}
+		if t, ok := prev.StatusChange[pkg]; ok {
+			a.OrphanedAt = common.Ptr(t.UTC())
+		}
+		adoptions = append(adoptions, a)
+	}
+	return adoptions, nil
+}
@@ -0,0 +1,55 @@
+package actions
+
+import (
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/fasjson"
+	"go.gtmx.me/goorphans/notifs"
+)
+
+// AffectedUser is a user's or group's exposure to the orphaned packages.
+type AffectedUser struct {
+	*notifs.UserTemplateData
+	// The @group that was expanded to find this user, if any
+	Group string `json:"group,omitempty"`
+}
+
+// Affected returns whether the user co-maintains or depends on any orphans.
+func (a *AffectedUser) Affected() bool {
+	return len(a.Orphaned) > 0 || len(a.Indirect) > 0
+}
+
+// GetAffectedUsers returns the direct and indirect exposure of each user or
+// @group in names.
+// Groups are listed followed by each of their members.
+func GetAffectedUsers(
+	f *fasjson.EmailCacheClient,
+	o *common.Orphans,
+	names []string,
+) ([]AffectedUser, error) {
+	var results []AffectedUser
+	seen := mapset.NewThreadUnsafeSet[string]()
+	add := func(name, group string) {
+		if seen.Add(name) {
+			td := notifs.GetUserTemplateData(o, name)
+			results = append(results, AffectedUser{td, group})
+		}
+	}
+	for _, name := range names {
+		add(name, "")
+		group, found := strings.CutPrefix(name, "@")
+		if !found {
+			continue
+		}
+		members, err := f.GetMembers(group)
+		if err != nil {
+			return results, err
+		}
+		for _, member := range members {
+			add(member, name)
+		}
+	}
+	return results, nil
+}
@@ -1,7 +1,10 @@
 package actions
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"net/url"
 	"os"
@@ -10,12 +13,114 @@ import (
 	"go.gtmx.me/goorphans/common"
 )
 
+// downloadStateFile stores the HTTP cache validators of the files downloaded
+// to a directory so unchanged files aren't fetched again.
+const downloadStateFile = ".download-state.json"
+
+type downloadState map[string]*common.DownloadValidators
+
+func loadDownloadState(dir string) downloadState {
+	state := downloadState{}
+	data, err := os.ReadFile(path.Join(dir, downloadStateFile))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("ignoring download state: %v", err)
+		}
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("ignoring invalid download state: %v", err)
+		return downloadState{}
+	}
+	return state
+}
+
+func (state downloadState) save(dir string) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	p := path.Join(dir, downloadStateFile)
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// checkSameRun returns an error if the orphans.txt file at txtPath was not
+// generated by the same run as o.
+func checkSameRun(o *common.Orphans, txtPath string) error {
+	f, err := os.Open(txtPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rt, err := common.ParseReportTimes(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", common.OrphansTXT, err)
+	}
+	if rt.StartedAt == nil && rt.FinishedAt == nil {
+		log.Printf("%s has no timestamps; can't check that it matches %s",
+			common.OrphansTXT, common.OrphansJSON)
+	}
+	if !o.SameRun(rt) {
+		return fmt.Errorf(
+			"%s and %s are from different runs; the data may be in the middle of"+
+				" being updated, so try again later",
+			common.OrphansTXT, common.OrphansJSON,
+		)
+	}
+	return nil
+}
+
+// downloadRetired downloads the optional list of retired packages from
+// baseurl to dir.
+// It's not an error if the server doesn't have the file.
+func downloadRetired(
+	httpclient *http.Client,
+	baseurl, dir string,
+	state downloadState,
+) error {
+	dlurl, err := url.JoinPath(baseurl, common.RetiredFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse url: %v", err)
+	}
+	dlpath := path.Join(dir, common.RetiredFile)
+	var validators *common.DownloadValidators
+	if _, err := os.Stat(dlpath); err == nil {
+		validators = state[common.RetiredFile]
+	}
+	tmp, validators, err := common.DownloadToTemp(httpclient, dlurl, dlpath, validators)
+	var serr *common.StatusCodeError
+	if errors.As(err, &serr) && serr.StatusCode == http.StatusNotFound {
+		log.Printf("%s does not exist", dlurl)
+		delete(state, common.RetiredFile)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	state[common.RetiredFile] = validators
+	if tmp == "" {
+		return nil
+	}
+	if err := os.Rename(tmp, dlpath); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
 func Download(httpclient *http.Client, baseurl, dir string) error {
 	_, err := DownloadWithOrphans(httpclient, baseurl, dir)
 	return err
 }
 
-// DownloadWithOrphans downloads data and return pared Orphans data
+// DownloadWithOrphans downloads data and return pared Orphans data.
+// Files are only fetched again if the server reports that they have changed.
+// The existing files are only replaced once both files have been downloaded,
+// orphans.json has been parsed, and both files are from the same run.
 func DownloadWithOrphans(
 	httpclient *http.Client,
 	baseurl, dir string,
@@ -25,25 +130,72 @@ func DownloadWithOrphans(
 	if err != nil {
 		return orphans, err
 	}
-	for _, fn := range []string{common.OrphansJSON, common.OrphansTXT} {
+	state := loadDownloadState(dir)
+	files := []string{common.OrphansJSON, common.OrphansTXT}
+	// Temporary files that haven't been moved into place yet
+	temps := map[string]string{}
+	defer func() {
+		for _, tmp := range temps {
+			_ = os.Remove(tmp)
+		}
+	}()
+	for _, fn := range files {
 		dlurl, err := url.JoinPath(baseurl, fn)
 		if err != nil {
 			return orphans, fmt.Errorf("failed to parse url: %v", err)
 		}
 		dlpath := path.Join(dir, fn)
 
-		err = common.DownloadFile(httpclient, dlurl, dlpath)
+		// Only revalidate files that still exist
+		var validators *common.DownloadValidators
+		if _, err := os.Stat(dlpath); err == nil {
+			validators = state[fn]
+		}
+		tmp, validators, err := common.DownloadToTemp(
+			httpclient,
+			dlurl,
+			dlpath,
+			validators,
+		)
 		if err != nil {
 			return orphans, err
 		}
+		if tmp != "" {
+			temps[fn] = tmp
+		}
+		state[fn] = validators
+	}
+	current := func(fn string) string {
+		if tmp, ok := temps[fn]; ok {
+			return tmp
+		}
+		return path.Join(dir, fn)
+	}
 
-		// Make sure the downloaded data is valid
-		if fn == common.OrphansJSON {
-			orphans, err = common.LoadOrphans(dlpath)
-			if err != nil {
-				return orphans, err
-			}
+	// Make sure the downloaded data is valid
+	orphans, err = common.LoadOrphans(current(common.OrphansJSON))
+	if err != nil {
+		return orphans, err
+	}
+	if err := downloadRetired(httpclient, baseurl, dir, state); err != nil {
+		log.Printf("failed to download %s: %v", common.RetiredFile, err)
+	}
+	if len(temps) == 0 {
+		log.Printf("orphans data has not changed")
+		return orphans, state.save(dir)
+	}
+	if err := checkSameRun(orphans, current(common.OrphansTXT)); err != nil {
+		return nil, err
+	}
+	for _, fn := range files {
+		tmp, ok := temps[fn]
+		if !ok {
+			continue
+		}
+		if err := os.Rename(tmp, path.Join(dir, fn)); err != nil {
+			return orphans, err
 		}
+		delete(temps, fn)
 	}
-	return orphans, err
+	return orphans, state.save(dir)
 }
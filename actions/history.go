@@ -0,0 +1,17 @@
+package actions
+
+import (
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/history"
+)
+
+// RecordHistory stores o in the history database at dbPath.
+// It returns false if the snapshot was already recorded.
+func RecordHistory(dbPath string, o *common.Orphans) (bool, error) {
+	h, err := history.Open(dbPath)
+	if err != nil {
+		return false, err
+	}
+	defer h.Close()
+	return h.Add(o)
+}
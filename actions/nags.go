@@ -63,5 +63,9 @@ func Send2FANag(ctx context.Context, config *config.Config, dataPath string) err
 	if err != nil {
 		return err
 	}
+	dryRun := ourmail.DryRunFrom(ctx)
+	for _, tu := range data {
+		dryRun.AddRecipients("provenpackager without 2FA", tu.Email)
+	}
 	return ourmail.SendMsg(ctx, config, msgs...)
 }
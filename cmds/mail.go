@@ -0,0 +1,281 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/mail"
+)
+
+func newMailCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mail",
+		Short: "Manage outgoing mail",
+	}
+	cmd.AddCommand(mailQueue())
+	return cmd
+}
+
+func openOutbox(cmd *cobra.Command) (*mail.Outbox, error) {
+	rargs := cmd.Context().Value(rootArgsKey).(*RootArgs)
+	return mail.OpenOutbox(rargs.Config.SMTP.QueueDB)
+}
+
+// messageIDs adds the angle brackets to Message-IDs passed without them.
+func messageIDs(argv []string) []string {
+	ids := make([]string, 0, len(argv))
+	for _, id := range argv {
+		if !strings.HasPrefix(id, "<") {
+			id = "<" + id + ">"
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// flushOutbox sends the queued messages in outbox.
+func flushOutbox(cmd *cobra.Command, outbox *mail.Outbox) error {
+	rargs := cmd.Context().Value(rootArgsKey).(*RootArgs)
+	if err := rargs.Config.SMTP.Validate(); err != nil {
+		return err
+	}
+	t, err := mail.NewTransport(&rargs.Config.SMTP)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	n, err := outbox.Flush(cmd.Context(), t)
+	colorToStderrForce(color.FgMagenta, "%d messages sent\n", n)
+	return err
+}
+
+// previewOutbox prints the messages with one of statuses and one of ids, or
+// all messages with one of statuses if ids is empty, for --dry-run.
+func previewOutbox(
+	cmd *cobra.Command,
+	outbox *mail.Outbox,
+	statuses []mail.Status,
+	ids []string,
+) error {
+	msgs, err := outbox.List(statuses...)
+	if err != nil {
+		return err
+	}
+	envelopes := make([]*mail.Envelope, 0, len(msgs))
+	for _, m := range msgs {
+		if len(ids) > 0 && !slices.Contains(ids, m.MessageID) {
+			continue
+		}
+		e, err := outbox.Load(m)
+		if err != nil {
+			return err
+		}
+		envelopes = append(envelopes, e)
+	}
+	return mail.DryRunFrom(cmd.Context()).Show(envelopes)
+}
+
+func mailQueue() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "queue",
+		Short: "Manage the persistent outbox",
+		Long: `Manage the persistent outbox.
+
+When smtp.queue is enabled, messages are added to the outbox at smtp.queue-db
+before they are sent and marked as sent, failed, or rejected with the server's
+response.
+If a run is interrupted, use "mail queue flush" to send the remaining messages
+instead of running the command again.`,
+	}
+	cmd.AddCommand(mailQueueList())
+	cmd.AddCommand(mailQueueFlush())
+	cmd.AddCommand(mailQueueRetry())
+	cmd.AddCommand(mailQueueDrop())
+	return cmd
+}
+
+func mailQueueList() *cobra.Command {
+	asJSON := false
+	var statuses []string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List messages in the outbox",
+		Args:  NoArgs,
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			filter := make([]mail.Status, 0, len(statuses))
+			for _, s := range statuses {
+				var status mail.Status
+				if err := status.UnmarshalText([]byte(s)); err != nil {
+					return err
+				}
+				filter = append(filter, status)
+			}
+			outbox, err := openOutbox(cmd)
+			if err != nil {
+				return err
+			}
+			defer outbox.Close()
+			msgs, err := outbox.List(filter...)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return JSONToStdout(msgs)
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, err = fmt.Fprintln(
+				w,
+				"MESSAGE-ID\tSTATUS\tUPDATED\tRECIPIENTS\tSUBJECT\tRESPONSE",
+			)
+			if err != nil {
+				return err
+			}
+			for _, m := range msgs {
+				_, err = fmt.Fprintf(
+					w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+					m.MessageID,
+					m.Status,
+					m.UpdatedAt.Local().Format(time.DateTime),
+					len(m.Recipients),
+					m.Subject,
+					m.Response,
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", asJSON, "Print the messages as JSON")
+	cmd.Flags().StringSliceVar(
+		&statuses, "status", nil,
+		"Only list messages with these statuses"+
+			" (queued, sending, sent, failed, rejected, or interrupted)",
+	)
+	return cmd
+}
+
+func mailQueueFlush() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flush",
+		Short: "Send the queued messages",
+		Long: `Send the queued messages.
+
+Messages that failed or were interrupted while sending are not sent again; use
+"mail queue retry" for those.`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			outbox, err := openOutbox(cmd)
+			if err != nil {
+				return err
+			}
+			defer outbox.Close()
+			n, err := outbox.Recover()
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				colorToStderrForce(
+					color.FgYellow,
+					"%d messages were interrupted while sending and were marked as interrupted\n",
+					n,
+				)
+			}
+			if mail.DryRunFrom(cmd.Context()) != nil {
+				return previewOutbox(cmd, outbox, []mail.Status{mail.StatusQueued}, nil)
+			}
+			return flushOutbox(cmd, outbox)
+		},
+	}
+	return cmd
+}
+
+func mailQueueRetry() *cobra.Command {
+	interrupted := false
+	cmd := &cobra.Command{
+		Use:   "retry [MESSAGE-ID...]",
+		Short: "Queue failed messages again and send them",
+		Long: `Queue failed messages again and send them.
+
+All failed messages are retried if no Message-IDs are given.
+Messages that were interrupted while sending may have been delivered, so they
+are only retried if their Message-IDs are given or --include-interrupted is
+passed.`,
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			outbox, err := openOutbox(cmd)
+			if err != nil {
+				return err
+			}
+			defer outbox.Close()
+			if _, err = outbox.Recover(); err != nil {
+				return err
+			}
+			ids := messageIDs(argv)
+			statuses := []mail.Status{mail.StatusFailed}
+			if interrupted || len(ids) > 0 {
+				statuses = append(statuses, mail.StatusInterrupted)
+			}
+			if mail.DryRunFrom(cmd.Context()) != nil {
+				return previewOutbox(cmd, outbox, statuses, ids)
+			}
+			n, err := outbox.Retry(interrupted, ids...)
+			if err != nil {
+				return err
+			}
+			colorToStderrForce(color.FgMagenta, "%d messages queued again\n", n)
+			return flushOutbox(cmd, outbox)
+		},
+	}
+	cmd.Flags().BoolVar(
+		&interrupted, "include-interrupted", interrupted,
+		"Also retry all messages that were interrupted while sending",
+	)
+	return cmd
+}
+
+func mailQueueDrop() *cobra.Command {
+	var status string
+	cmd := &cobra.Command{
+		Use:   "drop [MESSAGE-ID...]",
+		Short: "Remove messages from the outbox",
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			if (len(argv) > 0) == (status != "") {
+				return errors.New("pass either Message-IDs or --status")
+			}
+			outbox, err := openOutbox(cmd)
+			if err != nil {
+				return err
+			}
+			defer outbox.Close()
+			var n int
+			if status != "" {
+				var s mail.Status
+				if err = s.UnmarshalText([]byte(status)); err != nil {
+					return err
+				}
+				n, err = outbox.DropStatus(s)
+			} else {
+				n, err = outbox.Drop(messageIDs(argv)...)
+			}
+			if err != nil {
+				return err
+			}
+			colorToStderrForce(color.FgMagenta, "%d messages removed\n", n)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(
+		&status, "status", "",
+		"Remove all messages with this status"+
+			" (queued, sent, failed, rejected, or interrupted)",
+	)
+	return cmd
+}
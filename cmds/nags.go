@@ -11,7 +11,7 @@ func newNagsCmd() *cobra.Command {
 		Short: "Send reminder emails to Fedora packagers for various purposes",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			rargs := cmd.Context().Value(rootArgsKey).(*RootArgs)
-			if err := rargs.Config.SMTP.Validate(); err != nil {
+			if err := rargs.validateSMTP(); err != nil {
 				return err
 			}
 			return nil
@@ -1,14 +1,19 @@
 package cmds
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"maps"
 	"os"
 	"path"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,8 +24,9 @@ import (
 	"go.gtmx.me/goorphans/common"
 	"go.gtmx.me/goorphans/config"
 	"go.gtmx.me/goorphans/fasjson"
+	"go.gtmx.me/goorphans/history"
 	"go.gtmx.me/goorphans/mail"
-	"go.gtmx.me/goorphans/notifs"
+	"go.gtmx.me/goorphans/report"
 )
 
 var orphansArgsKey = &argsKeyType{"orphans"}
@@ -46,6 +52,7 @@ func (args *OrphansArgs) OrphansData() (*common.Orphans, error) {
 			return o, err
 		}
 		args.orphansData = o
+		args.recordHistory(o)
 		return o, nil
 	}
 	o, err := common.LoadOrphans(path.Join(args.Dir, common.OrphansJSON))
@@ -62,6 +69,24 @@ func (args *OrphansArgs) OrphansData() (*common.Orphans, error) {
 	return o, err
 }
 
+// recordHistory stores o in the history database.
+// Failures are only reported as warnings so they don't block other commands.
+func (args *OrphansArgs) recordHistory(o *common.Orphans) {
+	if args.Config.HistoryDB == "" {
+		return
+	}
+	if _, err := actions.RecordHistory(args.Config.HistoryDB, o); err != nil {
+		colorToStderrForce(color.FgYellow, "Failed to record orphans history: %v\n", err)
+	}
+}
+
+func (args *OrphansArgs) History() (*history.Store, error) {
+	if args.Config.HistoryDB == "" {
+		return nil, fmt.Errorf("orphans.history-db is not configured")
+	}
+	return history.Open(args.Config.HistoryDB)
+}
+
 func newOrphansCommand() *cobra.Command {
 	var baseurl string
 	var download bool
@@ -100,6 +125,17 @@ func newOrphansCommand() *cobra.Command {
 	cmd.AddCommand(oAnnounce())
 	cmd.AddCommand(oNotifications())
 	cmd.AddCommand(oJSON())
+	cmd.AddCommand(oHistory())
+	cmd.AddCommand(oDiff())
+	cmd.AddCommand(oDeadlines())
+	cmd.AddCommand(oWhy())
+	cmd.AddCommand(oAffected())
+	cmd.AddCommand(oValidate())
+	cmd.AddCommand(oGenerate())
+	cmd.AddCommand(oRender())
+	cmd.AddCommand(oRetireStatus())
+	cmd.AddCommand(oAdoptions())
+	cmd.AddCommand(oEPEL())
 	return cmd
 }
 
@@ -158,6 +194,24 @@ func emails(cache *fasjson.EmailCacheClient, data *common.Orphans) ([]string, er
 	return slices.Sorted(maps.Values(emailm)), nil
 }
 
+// checkFresh returns an error if o is too old or incomplete, unless allowStale
+// is set, in which case only a warning is printed.
+func (args *OrphansArgs) checkFresh(o *common.Orphans, allowStale bool) error {
+	maxAge := time.Duration(args.Config.MaxAge * float64(time.Hour))
+	err := o.CheckFresh(maxAge, time.Now())
+	if err == nil {
+		return nil
+	}
+	if allowStale {
+		colorToStderrForce(color.FgYellow, "Warning: %v\n", err)
+		return nil
+	}
+	return fmt.Errorf("%w; refresh the data with --download or pass --allow-stale", err)
+}
+
+const allowStaleUsage = "Use the orphans data even if it's older than orphans.max-age" +
+	" or incomplete"
+
 func oLastUpdated() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "last-updated",
@@ -210,11 +264,65 @@ var completeGolangExemption = cobra.FixedCompletions(
 	cobra.ShellCompDirectiveNoFileComp,
 )
 
+var listFormats = []string{"lines", "json", "csv", "tsv"}
+
+var completeListFormat = cobra.FixedCompletions(
+	listFormats, cobra.ShellCompDirectiveNoFileComp,
+)
+
+// writeOrphanRows writes rows as CSV with the given field separator
+func writeOrphanRows(w io.Writer, rows []common.OrphanRow, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	err := cw.Write([]string{
+		"package",
+		"status_change",
+		"retire_at",
+		"golang_exemption",
+		"exemptions",
+		"categories",
+		"maintainers",
+	})
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		statusChange, retireAt := "", ""
+		if row.StatusChange != nil {
+			statusChange = row.StatusChange.Format(time.RFC3339)
+		}
+		if row.RetireAt != nil {
+			retireAt = row.RetireAt.Format(time.RFC3339)
+		}
+		categories := make([]string, 0, len(row.Categories))
+		for _, c := range row.Categories {
+			categories = append(categories, string(c))
+		}
+		err = cw.Write([]string{
+			row.Package,
+			statusChange,
+			retireAt,
+			strconv.FormatBool(row.GolangExemption),
+			strings.Join(row.Exemptions, " "),
+			strings.Join(categories, " "),
+			strings.Join(row.Maintainers, " "),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
 func oList() *cobra.Command {
 	var out string
-	ge := common.GolangExemptionFlagDate
-	weeks := 6
+	var exemptions *exemptionFlags
+	var categories *categoryFlags
+	weeks := common.RetirementWeeks
 	count := false
+	format := "lines"
+	allowStale := false
 
 	cmd := &cobra.Command{
 		Use:     "list",
@@ -230,84 +338,210 @@ func oList() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			r, err := o.OrphanedFilter(
-				common.OrphanedFilterOptions{
-					Duration:        common.Weeks(weeks),
-					GolangExemption: ge,
-				},
-			)
+			if err := args.checkFresh(o, allowStale); err != nil {
+				return err
+			}
+			options, err := exemptions.options(args, o, common.Weeks(weeks))
+			if err != nil {
+				return err
+			}
+			if err = categories.apply(&options); err != nil {
+				return err
+			}
+			r, err := o.OrphanedFilter(options)
 			if err != nil {
 				return err
 			}
 			if count {
 				fmt.Println(len(r))
-			} else {
+				return nil
+			}
+			switch format {
+			case "lines":
 				err = common.WriteFileLines(out, r)
+			case "json", "csv", "tsv":
+				var rows []common.OrphanRow
+				// --weeks only filters the list; always report the real
+				// retirement date.
+				options.Duration = common.Weeks(common.RetirementWeeks)
+				rows, err = o.Rows(r, options)
 				if err != nil {
 					return err
 				}
-				colorToStderrF(color.FgMagenta, "    %d orphans listed\n", len(r))
+				err = common.WriteFileFunc(out, func(w io.Writer) error {
+					switch format {
+					case "json":
+						enc := json.NewEncoder(w)
+						enc.SetIndent("", "  ")
+						return enc.Encode(rows)
+					case "tsv":
+						return writeOrphanRows(w, rows, '\t')
+					default:
+						return writeOrphanRows(w, rows, ',')
+					}
+				})
+			default:
+				return fmt.Errorf(
+					"invalid --format %q: must be one of %s",
+					format, strings.Join(listFormats, ", "),
+				)
+			}
+			if err != nil {
+				return err
 			}
+			colorToStderrF(color.FgMagenta, "    %d orphans listed\n", len(r))
 			return nil
 		},
 	}
 	cmd.Flags().
 		StringVarP(&out, "output", "o", "-", "Output file; defaults to stdout")
 	cmd.Flags().IntVarP(&weeks, "weeks", "w", weeks, "")
-	cmd.Flags().TextVar(&ge, "golang-exemption", ge,
-		"flagdate (default), must, optional, ignore, or only",
-	)
+	exemptions = newExemptionFlags(cmd)
+	categories = newCategoryFlags(cmd)
 	cmd.Flags().BoolVar(&count, "count", count, "Only print a count")
-	_ = cmd.RegisterFlagCompletionFunc("golang-exemption", completeGolangExemption)
+	cmd.Flags().StringVarP(
+		&format, "format", "f", format,
+		"Output format: lines (package names only), json, csv, or tsv",
+	)
+	cmd.Flags().BoolVar(&allowStale, "allow-stale", allowStale, allowStaleUsage)
+	_ = cmd.RegisterFlagCompletionFunc("format", completeListFormat)
 	return cmd
 }
 
+// setAnnounceRecipients sends msg to the to addresses and BCCs the bcc
+// addresses and people.
+// If forceTo is not nil, the message is only sent to forceTo.
+// If any of people can't be resolved, it fails unless this is a dry run, in
+// which case they're recorded in the [mail.DryRun] report.
+func setAnnounceRecipients(
+	ctx context.Context,
+	msg *gomail.Msg,
+	f *fasjson.EmailCacheClient,
+	to []string,
+	replyTo string,
+	bcc []string,
+	people iter.Seq[string],
+	forceTo []string,
+) error {
+	resolved, err := f.ResolveEmails(people)
+	if err != nil {
+		return err
+	}
+	dryRun := mail.DryRunFrom(ctx)
+	if len(resolved.Unresolved) > 0 {
+		if dryRun == nil {
+			names := slices.Sorted(maps.Keys(resolved.Unresolved))
+			return fmt.Errorf(
+				"failed to resolve the email addresses of %s",
+				strings.Join(names, ", "),
+			)
+		}
+		for name, err := range resolved.Unresolved {
+			dryRun.AddUnresolved(name, err)
+		}
+	}
+	if forceTo != nil {
+		dryRun.AddRecipients(mail.ReasonTo, forceTo...)
+		return msg.To(forceTo...)
+	}
+	if err := msg.To(to...); err != nil {
+		return err
+	}
+	if err := msg.ReplyTo(replyTo); err != nil {
+		return err
+	}
+	dryRun.AddRecipients(mail.ReasonTo, to...)
+	dryRun.AddRecipients(mail.ReasonConfigBCC, bcc...)
+	dryRun.AddRecipients(
+		mail.ReasonMaintainer,
+		slices.Collect(maps.Values(resolved.Direct))...,
+	)
+	dryRun.AddRecipients(
+		mail.ReasonGroupMember,
+		slices.Collect(maps.Values(resolved.Members))...,
+	)
+	allBCC := make([]string, 0, len(bcc)+len(resolved.Direct)+len(resolved.Members))
+	allBCC = append(allBCC, bcc...)
+	allBCC = slices.AppendSeq(allBCC, maps.Values(resolved.Direct))
+	allBCC = slices.AppendSeq(allBCC, maps.Values(resolved.Members))
+	return msg.Bcc(allBCC...)
+}
+
 // makeAnnounceMsg prepares a [gomail.Msg] struct.
 // Set noRecpts to avoid sending to any recipients and only the BCC value from
 // the config.
 func makeAnnounceMsg(
+	ctx context.Context,
 	config *config.Config,
 	o *common.Orphans,
 	f *fasjson.EmailCacheClient,
+	subject string,
 	forceTo []string,
 ) (*gomail.Msg, error) {
 	affected := o.AllAffectedPeople
 	msg := gomail.NewMsg(gomail.WithNoDefaultUserAgent())
-	msg.Subject("Orphaned packages looking for new maintainers")
+	msg.Subject(subject)
 
 	if config.Orphans.DirectMaintsOnly {
 		affected = o.AffectedPeople
 	}
-	emails, err := f.GetIterEmailsMap(maps.Keys(affected))
+	err := setAnnounceRecipients(
+		ctx, msg, f,
+		config.Orphans.To, config.Orphans.ReplyTo, config.Orphans.BCC,
+		maps.Keys(affected), forceTo,
+	)
+	return msg, err
+}
+
+// announceData prepares the data for [report.AnnounceTemplate].
+// The full report is rendered from orphans.json if render is set and taken
+// from orphans.txt otherwise.
+func (args *OrphansArgs) announceData(
+	o *common.Orphans,
+	render bool,
+	options common.OrphanedFilterOptions,
+	adoptions []common.Adoption,
+) (*report.AnnounceData, error) {
+	deadlines, err := o.Deadlines(options)
 	if err != nil {
-		return msg, err
+		return nil, err
 	}
-	if forceTo == nil {
-		if err := msg.To(config.Orphans.To...); err != nil {
-			return msg, err
-		}
-		if err := msg.ReplyTo(config.Orphans.ReplyTo); err != nil {
-			return msg, err
-		}
-		bcc := make([]string, 0, len(config.Orphans.BCC)+len(emails))
-		bcc = append(bcc, config.Orphans.BCC...)
-		for _, email := range emails {
-			bcc = append(bcc, email)
+	// orphans.txt is only parsed when the report is rendered from orphans.json
+	// since it's sent verbatim otherwise.
+	var tree *common.DepTree
+	if render {
+		if tree, err = args.depTree(); err != nil {
+			return nil, err
 		}
-		if err := msg.Bcc(bcc...); err != nil {
-			return msg, err
+	}
+	data := report.NewAnnounceData(report.NewData(o, tree), deadlines)
+	data.BaseURL = args.Config.BaseURL
+	data.Adoptions = adoptions
+	if render {
+		var b bytes.Buffer
+		if err = report.Render(&b, report.TXTTemplate, data.Data); err != nil {
+			return nil, err
 		}
+		data.Report = b.String()
 	} else {
-		if err := msg.To(forceTo...); err != nil {
-			return msg, err
+		txt, err := os.ReadFile(path.Join(args.Dir, common.OrphansTXT))
+		if err != nil {
+			return nil, err
 		}
+		data.Report = string(txt)
 	}
-	return msg, nil
+	return data, nil
 }
 
 func oAnnounce() *cobra.Command {
 	direct := false
+	skipValidation := false
+	allowStale := false
+	render := false
+	adoptions := true
+	newThread := false
 	var forceTo []string
+	var exemptions *exemptionFlags
 	cmd := &cobra.Command{
 		Use:   "announce",
 		Short: "Send announcement",
@@ -323,29 +557,80 @@ func oAnnounce() *cobra.Command {
 				return err
 			}
 			lastUpdated(o, os.Stderr)
+			if err := args.checkFresh(o, allowStale); err != nil {
+				return err
+			}
+			if !skipValidation {
+				// orphans.txt is optional when the report is rendered, but it's
+				// still checked if it exists since the dependency trees come
+				// from it.
+				checkTxt := true
+				if render {
+					_, err := os.Stat(path.Join(args.Dir, common.OrphansTXT))
+					checkTxt = !errors.Is(err, os.ErrNotExist)
+				}
+				if err := args.validate(checkTxt); err != nil {
+					return err
+				}
+			}
 
-			f, err := args.RootArgs.FASCache()
+			var adopted []history.Adoption
+			if adoptions {
+				if adopted, err = args.unannouncedAdoptions(); err != nil {
+					return err
+				}
+			}
+			options, err := exemptions.options(
+				args, o, common.Weeks(common.RetirementWeeks),
+			)
+			if err != nil {
+				return err
+			}
+			data, err := args.announceData(o, render, options, commonAdoptions(adopted))
 			if err != nil {
 				return err
 			}
-			msg, err := makeAnnounceMsg(args.RootArgs.Config, o, f, forceTo)
+			subject, err := report.RenderSubject(args.Config.Subject, data)
 			if err != nil {
+				return fmt.Errorf("failed to render orphans.subject: %w", err)
+			}
+			var body bytes.Buffer
+			if err = report.AnnounceTemplate.Execute(&body, data); err != nil {
 				return err
 			}
 
-			p := path.Join(args.Dir, common.OrphansTXT)
-			err = mail.MsgSetBodyFromFile(msg, p)
+			f, err := args.RootArgs.FASCache()
+			if err != nil {
+				return err
+			}
+			msg, err := makeAnnounceMsg(
+				cmd.Context(), args.RootArgs.Config, o, f, subject, forceTo,
+			)
 			if err != nil {
 				return err
 			}
+			msg.SetBodyString(gomail.TypeTextPlain, body.String())
+			var thread *announceThread
+			if !newThread {
+				if thread, err = args.announceThread(time.Now()); err != nil {
+					return err
+				}
+			}
+			if thread != nil {
+				thread.setHeaders(msg)
+			}
 
-			if err := args.RootArgs.Config.SMTP.Validate(); err != nil {
+			if err := args.RootArgs.validateSMTP(); err != nil {
 				return err
 			}
 			err = mail.SendMsg(cmd.Context(), args.RootArgs.Config, msg)
 			if err != nil {
 				return err
 			}
+			if forceTo == nil && !args.RootArgs.DryRun {
+				args.markAnnounced(adopted)
+				args.recordAnnouncement(msg, subject, thread)
+			}
 			return nil
 		},
 	}
@@ -360,60 +645,26 @@ func oAnnounce() *cobra.Command {
 			&forceTo, "force-to", nil,
 			"Only send message to address and don't BCC maintainers",
 		)
-	return cmd
-}
-
-func writeTemplate(outdir string, user string, o *common.Orphans) error {
-	f, err := os.Create(path.Join(outdir, fmt.Sprintf("%s.txt", user)))
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	td := notifs.GetUserTemplateData(o, user)
-	err = notifs.UserTemplate.Execute(f, &td)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-// WIP
-// See https://lists.fedoraproject.org/archives/list/devel@lists.fedoraproject.org/message/QD3HH77G2TBXAOTMLN2LMN6W453REEGB/
-func oNotifications() *cobra.Command {
-	outdir := "notifs-rendered"
-	cmd := &cobra.Command{
-		Use:     "notifications",
-		Aliases: []string{"notifs"},
-		Short:   "WIP command to send individual notifications",
-		RunE: func(cmd *cobra.Command, a []string) error {
-			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
-			err := os.MkdirAll(outdir, 0o755)
-			if err != nil {
-				return err
-			}
-			o, err := args.OrphansData()
-			if err != nil {
-				return err
-			}
-
-			// TODO: Actually send messages instead of writing to files.
-			// f, err := args.RootArgs.FASCache()
-			// if err != nil {
-			// 	return err
-
-			for user := range o.AllAffectedPeople {
-				if user[0] == '@' {
-					continue
-				}
-				err = writeTemplate(outdir, user, o)
-				if err != nil {
-					return err
-				}
-			}
-
-			return nil
-		},
-	}
+	cmd.Flags().BoolVar(
+		&skipValidation, "skip-validation", skipValidation,
+		"Send the announcement even if the orphans data fails validation",
+	)
+	cmd.Flags().BoolVar(&allowStale, "allow-stale", allowStale, allowStaleUsage)
+	cmd.Flags().BoolVar(
+		&render, "render", render,
+		"Render the report from orphans.json instead of sending orphans.txt verbatim",
+	)
+	cmd.Flags().BoolVar(
+		&adoptions, "adoptions", adoptions,
+		"List the packages adopted since the last announcement"+
+			" (recorded by orphans adoptions)",
+	)
+	cmd.Flags().BoolVar(
+		&newThread, "new-thread", newThread,
+		"Start a new thread instead of replying to the previous announcement"+
+			" (see orphans.thread)",
+	)
+	exemptions = newExemptionFlags(cmd)
 	return cmd
 }
 
@@ -0,0 +1,256 @@
+package cmds
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	gomail "github.com/wneessen/go-mail"
+	"go.gtmx.me/goorphans/actions"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/distgit"
+	"go.gtmx.me/goorphans/fasjson"
+	"go.gtmx.me/goorphans/history"
+	ourmail "go.gtmx.me/goorphans/mail"
+	"go.gtmx.me/goorphans/notifs"
+)
+
+// writeAdoptions writes a section listing adoptions and their new admins.
+// Nothing is written if adoptions is empty.
+func writeAdoptions(w io.Writer, title string, adoptions []common.Adoption) error {
+	if len(adoptions) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "%s (%d):\n", title, len(adoptions)); err != nil {
+		return err
+	}
+	for _, a := range adoptions {
+		if _, err := fmt.Fprintf(w, "    %s (@%s)\n", a.Package, a.Admin); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func commonAdoptions(adoptions []history.Adoption) []common.Adoption {
+	r := make([]common.Adoption, 0, len(adoptions))
+	for _, a := range adoptions {
+		r = append(r, a.Adoption)
+	}
+	return r
+}
+
+// unannouncedAdoptions returns the recorded adoptions that haven't been
+// included in an announcement yet.
+// It returns nil if the history database is not configured.
+func (args *OrphansArgs) unannouncedAdoptions() ([]history.Adoption, error) {
+	if args.Config.HistoryDB == "" {
+		return nil, nil
+	}
+	h, err := args.History()
+	if err != nil {
+		return nil, err
+	}
+	defer h.Close()
+	return h.UnannouncedAdoptions()
+}
+
+// markAnnounced records that adoptions were included in an announcement.
+// Failures are only reported as warnings, as the announcement was already
+// sent.
+func (args *OrphansArgs) markAnnounced(adoptions []history.Adoption) {
+	if len(adoptions) == 0 {
+		return
+	}
+	h, err := args.History()
+	if err == nil {
+		err = h.MarkAnnounced(adoptions, time.Now())
+		_ = h.Close()
+	}
+	if err != nil {
+		colorToStderrForce(
+			color.FgYellow,
+			"Failed to record announced adoptions: %v\n",
+			err,
+		)
+	}
+}
+
+// retiredPackages returns the packages from the retired file or nil if it
+// doesn't exist.
+func (args *OrphansArgs) retiredPackages() ([]string, error) {
+	r, err := common.LoadRetired(path.Join(args.Dir, common.RetiredFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return r.Packages, nil
+}
+
+func makeAdoptionMsgs(
+	ctx context.Context,
+	f *fasjson.EmailCacheClient,
+	adoptions []common.Adoption,
+) ([]*gomail.Msg, error) {
+	var msgs []*gomail.Msg
+	for _, td := range notifs.GetAdoptionTemplateData(adoptions) {
+		if td.User[0] == '@' {
+			continue
+		}
+		email, err := f.GetUserEmail(td.User)
+		if err != nil {
+			return msgs, err
+		}
+		msg := gomail.NewMsg(gomail.WithNoDefaultUserAgent())
+		msg.Subject(
+			fmt.Sprintf(notifs.AdoptionSubjectFmt, strings.Join(td.Packages, ", ")),
+		)
+		msg.ToMailAddress(&mail.Address{Name: td.User, Address: email})
+		ourmail.DryRunFrom(ctx).AddRecipients(ourmail.ReasonMaintainer, email)
+		if err = msg.SetBodyTextTemplate(notifs.AdoptionTemplate, td); err != nil {
+			return msgs, fmt.Errorf("failed to render template for %s: %w", td.User, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// notifyAdopters sends a confirmation mail to the adopters of the recorded
+// adoptions that haven't been notified yet.
+func (args *OrphansArgs) notifyAdopters(cmd *cobra.Command, h *history.Store) error {
+	pending, err := h.UnnotifiedAdoptions()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(os.Stderr, "No adopters to notify")
+		return nil
+	}
+	f, err := args.RootArgs.FASCache()
+	if err != nil {
+		return err
+	}
+	msgs, err := makeAdoptionMsgs(cmd.Context(), f, commonAdoptions(pending))
+	if err != nil {
+		return err
+	}
+	if err = args.RootArgs.validateSMTP(); err != nil {
+		return err
+	}
+	if err = ourmail.SendMsg(cmd.Context(), args.RootArgs.Config, msgs...); err != nil {
+		return err
+	}
+	if args.RootArgs.DryRun {
+		return nil
+	}
+	return h.MarkNotified(pending, time.Now())
+}
+
+func oAdoptions() *cobra.Command {
+	asJSON := false
+	record := true
+	notify := false
+	cmd := &cobra.Command{
+		Use:   "adoptions [OLD [NEW]]",
+		Short: "Find orphaned packages that were adopted and their new admins",
+		Long: `Find orphaned packages that were adopted and their new admins.
+
+Packages that are no longer orphaned and weren't retired were adopted.
+Their new point of contact is taken from distgit's pagure_poc.json.
+OLD and NEW are handled like in "orphans diff".
+
+Adoptions are recorded in the history database if it's configured so that
+the next "orphans announce" can list them.
+With --notify, the adopters that haven't received one yet are sent a short
+confirmation mail.`,
+		Args: ArgsWrapper(cobra.MaximumNArgs(2)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			if notify && (!record || args.Config.HistoryDB == "") {
+				return errors.New(
+					"--notify requires orphans.history-db and --record" +
+						" so adopters aren't notified twice",
+				)
+			}
+			var prev, cur *common.Orphans
+			var err error
+			if len(argv) == 2 {
+				cur, err = common.LoadOrphans(argv[1])
+			} else {
+				cur, err = args.OrphansData()
+			}
+			if err != nil {
+				return err
+			}
+			if len(argv) > 0 {
+				prev, err = common.LoadOrphans(argv[0])
+			} else {
+				prev, err = args.previousOrphans(cur)
+			}
+			if err != nil {
+				return err
+			}
+			retired, err := args.retiredPackages()
+			if err != nil {
+				return err
+			}
+			e := distgit.NewExtrasClient(args.RootArgs.HTTPClient)
+			adoptions, err := actions.FindAdoptions(prev, cur, e, retired)
+			if err != nil {
+				return err
+			}
+
+			if record && args.Config.HistoryDB != "" {
+				h, err := args.History()
+				if err != nil {
+					return err
+				}
+				defer h.Close()
+				n, err := h.AddAdoptions(adoptions)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Recorded %d new adoptions\n", n)
+				if notify {
+					if err = args.notifyAdopters(cmd, h); err != nil {
+						return err
+					}
+				}
+			}
+
+			if asJSON {
+				return JSONToStdout(adoptions)
+			}
+			w := bufio.NewWriter(os.Stdout)
+			if len(adoptions) == 0 {
+				if _, err = fmt.Fprintln(w, "No adoptions"); err != nil {
+					return err
+				}
+			} else if err = writeAdoptions(w, "Adopted", adoptions); err != nil {
+				return err
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", asJSON, "Print the adoptions as JSON")
+	cmd.Flags().BoolVar(
+		&record, "record", record,
+		"Record the adoptions in the history database",
+	)
+	cmd.Flags().BoolVar(
+		&notify, "notify", notify,
+		"Send a confirmation mail to adopters that haven't been notified yet",
+	)
+	return cmd
+}
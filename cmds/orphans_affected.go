@@ -0,0 +1,97 @@
+package cmds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/actions"
+)
+
+func writeAffectedUser(w io.Writer, a *actions.AffectedUser) error {
+	name := a.User
+	if a.Group != "" {
+		name = fmt.Sprintf("%s (member of %s)", a.User, a.Group)
+	}
+	if !a.Affected() {
+		_, err := fmt.Fprintf(w, "%s: not affected\n\n", name)
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s:\n", name); err != nil {
+		return err
+	}
+	sections := []struct {
+		title string
+		pkgs  []string
+	}{
+		{"Orphaned packages they co-maintain", a.Orphaned},
+		{"Orphans their packages depend on", a.Indirect},
+	}
+	for _, s := range sections {
+		if len(s.pkgs) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "  %s (%d):\n", s.title, len(s.pkgs)); err != nil {
+			return err
+		}
+		for _, pkg := range s.pkgs {
+			if _, err := fmt.Fprintf(w, "    %s\n", pkg); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func oAffected() *cobra.Command {
+	asJSON := false
+	onlyAffected := false
+	cmd := &cobra.Command{
+		Use:   "affected NAME...",
+		Short: "Show the orphans that affect users or groups (prefixed with @)",
+		Args:  ArgsWrapper(cobra.MinimumNArgs(1)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			o, err := args.OrphansData()
+			if err != nil {
+				return err
+			}
+			f, err := args.RootArgs.FASCache()
+			if err != nil {
+				return err
+			}
+			results, err := actions.GetAffectedUsers(f, o, argv)
+			if err != nil {
+				return err
+			}
+			if onlyAffected {
+				var filtered []actions.AffectedUser
+				for _, a := range results {
+					if a.Affected() {
+						filtered = append(filtered, a)
+					}
+				}
+				results = filtered
+			}
+			if asJSON {
+				return JSONToStdout(results)
+			}
+			w := bufio.NewWriter(os.Stdout)
+			for _, a := range results {
+				if err := writeAffectedUser(w, &a); err != nil {
+					return err
+				}
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", asJSON, "Print results as JSON")
+	cmd.Flags().BoolVarP(
+		&onlyAffected, "only-affected", "A", onlyAffected,
+		"Skip users that are not affected",
+	)
+	return cmd
+}
@@ -0,0 +1,59 @@
+package cmds
+
+import (
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/common"
+)
+
+// categoryFlags select or exclude orphans by their category in orphans.json.
+type categoryFlags struct {
+	include []string
+	exclude []string
+}
+
+func newCategoryFlags(cmd *cobra.Command) *categoryFlags {
+	f := &categoryFlags{}
+	cmd.Flags().StringSliceVar(
+		&f.include, "category", nil,
+		"Only include orphans in any of these categories (e.g., orphans_breaking_deps)",
+	)
+	cmd.Flags().StringSliceVar(
+		&f.exclude, "exclude-category", nil,
+		"Exclude orphans in any of these categories",
+	)
+	_ = cmd.RegisterFlagCompletionFunc("category", completeCategory)
+	_ = cmd.RegisterFlagCompletionFunc("exclude-category", completeCategory)
+	return f
+}
+
+func parseCategories(names []string) ([]common.Category, error) {
+	r := make([]common.Category, 0, len(names))
+	for _, name := range names {
+		var c common.Category
+		if err := c.UnmarshalText([]byte(name)); err != nil {
+			return nil, err
+		}
+		r = append(r, c)
+	}
+	return r, nil
+}
+
+// apply sets the category filters in options.
+func (f *categoryFlags) apply(options *common.OrphanedFilterOptions) error {
+	var err error
+	if options.Categories, err = parseCategories(f.include); err != nil {
+		return err
+	}
+	options.ExcludeCategories, err = parseCategories(f.exclude)
+	return err
+}
+
+func completeCategory(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	r := make([]string, 0, len(common.Categories))
+	for _, c := range common.Categories {
+		r = append(r, c.String())
+	}
+	return r, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,109 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/common"
+)
+
+func formatDaysRemaining(d *common.Deadline, now time.Time) string {
+	days := d.DaysRemaining(now)
+	switch {
+	case d.Eligible(now):
+		return "eligible"
+	case days == 1:
+		return "1 day"
+	default:
+		return fmt.Sprintf("%d days", days)
+	}
+}
+
+func oDeadlines() *cobra.Command {
+	var exemptions *exemptionFlags
+	var categories *categoryFlags
+	weeks := common.RetirementWeeks
+	asJSON := false
+	eligibleOnly := false
+	cmd := &cobra.Command{
+		Use:   "deadlines [PACKAGE...]",
+		Short: "Show when each orphan becomes eligible for retirement",
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			o, err := args.OrphansData()
+			if err != nil {
+				return err
+			}
+			options, err := exemptions.options(args, o, common.Weeks(weeks))
+			if err != nil {
+				return err
+			}
+			if err = categories.apply(&options); err != nil {
+				return err
+			}
+			deadlines, err := o.Deadlines(options)
+			if err != nil {
+				return err
+			}
+			now := time.Now().UTC()
+			deadlines = slices.DeleteFunc(deadlines, func(d common.Deadline) bool {
+				if eligibleOnly && !d.Eligible(now) {
+					return true
+				}
+				return len(argv) > 0 && !slices.Contains(argv, d.Package)
+			})
+			if len(argv) > len(deadlines) {
+				for _, pkg := range argv {
+					if !slices.ContainsFunc(deadlines, func(d common.Deadline) bool {
+						return d.Package == pkg
+					}) {
+						colorToStderrForce(
+							color.FgYellow,
+							"%s is not orphaned, is exempt, or is excluded\n",
+							pkg,
+						)
+					}
+				}
+			}
+			if asJSON {
+				return JSONToStdout(deadlines)
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, err = fmt.Fprintln(w, "PACKAGE\tSTATUS CHANGE\tRETIREMENT DATE\tREMAINING")
+			if err != nil {
+				return err
+			}
+			for _, d := range deadlines {
+				statusChange := "unknown"
+				if d.StatusChange != nil {
+					statusChange = d.StatusChange.Format(time.DateOnly)
+				}
+				_, err = fmt.Fprintf(
+					w, "%s\t%s\t%s\t%s\n",
+					d.Package,
+					statusChange,
+					d.RetireAt.Format(time.DateOnly),
+					formatDaysRemaining(&d, now),
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().IntVarP(&weeks, "weeks", "w", weeks, "Grace period in weeks")
+	exemptions = newExemptionFlags(cmd)
+	categories = newCategoryFlags(cmd)
+	cmd.Flags().BoolVar(&asJSON, "json", asJSON, "Print deadlines as JSON")
+	cmd.Flags().BoolVar(
+		&eligibleOnly, "eligible", eligibleOnly,
+		"Only show packages that are eligible for retirement",
+	)
+	return cmd
+}
@@ -0,0 +1,186 @@
+package cmds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/distgit"
+)
+
+func writeDiffSection(w io.Writer, title string, pkgs []string) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "%s (%d):\n", title, len(pkgs)); err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		if _, err := fmt.Fprintf(w, "    %s\n", pkg); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func formatFinishedAt(t *time.Time) string {
+	if t == nil {
+		return "unknown"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// writeDiff writes d in a human-readable format
+func writeDiff(file io.Writer, d *common.OrphansDiff) error {
+	w := bufio.NewWriter(file)
+	_, err := fmt.Fprintf(
+		w, "Comparing %s -> %s\n\n",
+		formatFinishedAt(d.OldFinishedAt), formatFinishedAt(d.NewFinishedAt),
+	)
+	if err != nil {
+		return err
+	}
+	if d.Empty() {
+		if _, err = fmt.Fprintln(w, "No changes"); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+	sections := []struct {
+		title string
+		pkgs  []string
+	}{
+		{"Newly orphaned", d.Orphaned},
+		{"Adopted", d.Adopted},
+		{"Retired", d.Retired},
+		{"No longer orphaned", d.Removed},
+		{"Now breaking dependencies", d.ToBreakingDeps},
+		{"No longer breaking dependencies", d.ToNotBreakingDeps},
+	}
+	for _, s := range sections {
+		if err = writeDiffSection(w, s.title, s.pkgs); err != nil {
+			return err
+		}
+	}
+	if len(d.AffectedPeople) > 0 {
+		_, err = fmt.Fprintf(w, "Affected people changes (%d):\n", len(d.AffectedPeople))
+		if err != nil {
+			return err
+		}
+		for _, person := range slices.Sorted(maps.Keys(d.AffectedPeople)) {
+			change := d.AffectedPeople[person]
+			parts := make([]string, 0, len(change.Added)+len(change.Removed))
+			for _, pkg := range change.Added {
+				parts = append(parts, "+"+pkg)
+			}
+			for _, pkg := range change.Removed {
+				parts = append(parts, "-"+pkg)
+			}
+			_, err = fmt.Fprintf(w, "    %s: %s\n", person, strings.Join(parts, " "))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// previousOrphans returns the newest snapshot in the history database that
+// finished before o.
+func (args *OrphansArgs) previousOrphans(o *common.Orphans) (*common.Orphans, error) {
+	if o.FinishedAt == nil {
+		return nil, fmt.Errorf("finished_at was not included in the orphans data")
+	}
+	h, err := args.History()
+	if err != nil {
+		return nil, err
+	}
+	defer h.Close()
+	key, err := h.FindBefore(o.FinishedAt.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find previous orphans data: %w", err)
+	}
+	return h.Get(key)
+}
+
+func oDiff() *cobra.Command {
+	asJSON := false
+	checkRetired := false
+	cmd := &cobra.Command{
+		Use:   "diff [OLD [NEW]]",
+		Short: "Compare two orphans.json files",
+		Long: `Compare two orphans.json files.
+
+If NEW is omitted, the current orphans data is used.
+If both are omitted, the current orphans data is compared to the previous
+snapshot in the history database.
+
+Packages that are no longer orphaned are sorted into adopted and retired
+using the retired list in --dir if it exists. Pass --check-retired to query
+distgit for each package instead.`,
+		Args: ArgsWrapper(cobra.MaximumNArgs(2)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			var prev, cur *common.Orphans
+			var err error
+			if len(argv) == 2 {
+				cur, err = common.LoadOrphans(argv[1])
+			} else {
+				cur, err = args.OrphansData()
+			}
+			if err != nil {
+				return err
+			}
+			if len(argv) > 0 {
+				prev, err = common.LoadOrphans(argv[0])
+			} else {
+				prev, err = args.previousOrphans(cur)
+			}
+			if err != nil {
+				return err
+			}
+
+			var options common.OrphansDiffOptions
+			if checkRetired {
+				e := distgit.NewExtrasClient(args.RootArgs.HTTPClient)
+				options.IsRetired = func(pkg string) (bool, error) {
+					return e.IsRetired(pkg, "rawhide")
+				}
+			} else {
+				retired, err := args.retiredPackages()
+				if err != nil {
+					return err
+				}
+				if retired != nil {
+					s := mapset.NewThreadUnsafeSet(retired...)
+					options.IsRetired = func(pkg string) (bool, error) {
+						return s.Contains(pkg), nil
+					}
+				}
+			}
+			d, err := common.DiffOrphans(prev, cur, options)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return JSONToStdout(d)
+			}
+			return writeDiff(os.Stdout, d)
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", asJSON, "Print the diff as JSON")
+	cmd.Flags().BoolVar(
+		&checkRetired, "check-retired", checkRetired,
+		"Check distgit to tell apart adopted and retired packages (one request per package)",
+	)
+	return cmd
+}
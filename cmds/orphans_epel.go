@@ -0,0 +1,129 @@
+package cmds
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"time"
+
+	"github.com/spf13/cobra"
+	gomail "github.com/wneessen/go-mail"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/generate"
+	"go.gtmx.me/goorphans/mail"
+	"go.gtmx.me/goorphans/report"
+)
+
+// epelOrphans finds the EPEL orphans using the distgit extras files from dir
+// or downloads them if dir is empty.
+func (args *OrphansArgs) epelOrphans(dir string) (*generate.EPELOrphans, error) {
+	data, err := args.getExtras(dir)
+	if err != nil {
+		return nil, err
+	}
+	maintainers := generate.Maintainers(data.owners, data.bz)
+	return generate.GenerateEPEL(data.poc, maintainers, time.Now()), nil
+}
+
+func oEPEL() *cobra.Command {
+	var extras string
+	cmd := &cobra.Command{
+		Use:   "epel",
+		Short: "Subcommands for packages that are only orphaned in EPEL",
+		Long: `Subcommands for packages that are only orphaned in EPEL.
+
+EPEL orphans are packages whose EPEL point of contact in distgit's
+pagure_poc.json is orphan while the Fedora point of contact is a real person.
+They are not included in orphans.json.`,
+	}
+	cmd.PersistentFlags().StringVar(
+		&extras, "extras", "",
+		"Load pagure_poc.json, pagure_owner_alias.json, and pagure_bz.json from"+
+			" this directory instead of downloading them",
+	)
+	cmd.AddCommand(oEPELList(&extras))
+	cmd.AddCommand(oEPELAnnounce(&extras))
+	return cmd
+}
+
+func oEPELList(extras *string) *cobra.Command {
+	asJSON := false
+	out := "-"
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List packages that are orphaned in EPEL and their maintainers",
+		Args:  NoArgs,
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			e, err := args.epelOrphans(*extras)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return JSONToStdout(e)
+			}
+			return common.WriteFileFunc(out, func(w io.Writer) error {
+				return report.EPELTemplate.Execute(w, e)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", asJSON, "Print the EPEL orphans as JSON")
+	cmd.Flags().StringVarP(&out, "output", "o", out, "Output file; defaults to stdout")
+	cmd.MarkFlagsMutuallyExclusive("json", "output")
+	return cmd
+}
+
+func oEPELAnnounce(extras *string) *cobra.Command {
+	var forceTo []string
+	cmd := &cobra.Command{
+		Use:   "announce",
+		Short: "Send the EPEL orphans announcement",
+		Long: `Send the EPEL orphans announcement.
+
+The announcement is sent to orphans.epel.to and BCCed to the maintainers of
+the EPEL orphans.`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			e, err := args.epelOrphans(*extras)
+			if err != nil {
+				return err
+			}
+			if len(e.Orphans) == 0 {
+				return errors.New("no packages are orphaned in EPEL")
+			}
+
+			f, err := args.RootArgs.FASCache()
+			if err != nil {
+				return err
+			}
+			config := args.Config.EPEL
+			msg := gomail.NewMsg(gomail.WithNoDefaultUserAgent())
+			msg.Subject("Packages orphaned in EPEL looking for new maintainers")
+			err = setAnnounceRecipients(
+				cmd.Context(), msg, f,
+				config.To, config.ReplyTo, config.BCC,
+				maps.Keys(e.AffectedPeople), forceTo,
+			)
+			if err != nil {
+				return err
+			}
+			var body bytes.Buffer
+			if err = report.EPELTemplate.Execute(&body, e); err != nil {
+				return err
+			}
+			msg.SetBodyString(gomail.TypeTextPlain, body.String())
+
+			if err := args.RootArgs.validateSMTP(); err != nil {
+				return err
+			}
+			return mail.SendMsg(cmd.Context(), args.RootArgs.Config, msg)
+		},
+	}
+	cmd.Flags().StringSliceVar(
+		&forceTo, "force-to", nil,
+		"Only send message to address and don't BCC maintainers",
+	)
+	return cmd
+}
@@ -0,0 +1,77 @@
+package cmds
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/common"
+)
+
+// exemptionFlags are the flags that control the exemption policies.
+type exemptionFlags struct {
+	golang common.GolangExemption
+	// NAME=MODE
+	modes []string
+}
+
+func newExemptionFlags(cmd *cobra.Command) *exemptionFlags {
+	f := &exemptionFlags{golang: common.GolangExemptionFlagDate}
+	cmd.Flags().TextVar(&f.golang, "golang-exemption", f.golang,
+		"flagdate (default), must, optional, ignore, or only",
+	)
+	cmd.Flags().StringArrayVar(
+		&f.modes, "exemption", nil,
+		"Override the mode of an exemption policy from the config as NAME=MODE",
+	)
+	_ = cmd.RegisterFlagCompletionFunc("golang-exemption", completeGolangExemption)
+	return f
+}
+
+// options returns the [common.OrphanedFilterOptions] for o with the
+// configured exemption policies.
+func (f *exemptionFlags) options(
+	args *OrphansArgs,
+	o *common.Orphans,
+	duration time.Duration,
+) (common.OrphanedFilterOptions, error) {
+	options := common.OrphanedFilterOptions{
+		Duration:        duration,
+		GolangExemption: f.golang,
+	}
+	policies, err := args.Config.ExemptionPolicies(o)
+	if err != nil {
+		return options, err
+	}
+	for _, m := range f.modes {
+		name, value, ok := strings.Cut(m, "=")
+		if !ok {
+			return options, fmt.Errorf("invalid --exemption %q: must be NAME=MODE", m)
+		}
+		var mode common.ExemptionMode
+		if err := mode.UnmarshalText([]byte(value)); err != nil {
+			return options, fmt.Errorf("invalid --exemption %q: %w", m, err)
+		}
+		found := false
+		for i := range policies {
+			if policies[i].Name == name {
+				policies[i].Mode = mode
+				found = true
+			}
+		}
+		switch {
+		case found:
+		case name == common.GolangExemptionPolicyName:
+			options.GolangExemption = mode
+		default:
+			return options, fmt.Errorf(
+				"invalid --exemption %q: unknown policy %q",
+				m,
+				name,
+			)
+		}
+	}
+	options.Exemptions = policies
+	return options, nil
+}
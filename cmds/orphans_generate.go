@@ -0,0 +1,173 @@
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/distgit"
+	"go.gtmx.me/goorphans/generate"
+)
+
+// loadExtrasFile loads a file in the format of
+// https://src.fedoraproject.org/extras/NAME.json from dir.
+func loadExtrasFile(dir string, name string, dest any) error {
+	data, err := os.ReadFile(path.Join(dir, name+".json"))
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("failed to decode %s.json: %w", name, err)
+	}
+	return nil
+}
+
+type extrasData struct {
+	poc    *distgit.ExtrasPagurePOC
+	owners *distgit.ExtrasPagureOwnerAlias
+	bz     *distgit.ExtrasPagureBZ
+}
+
+// getExtras loads the distgit extras files from dir or downloads them if dir
+// is empty.
+func (args *OrphansArgs) getExtras(dir string) (*extrasData, error) {
+	d := &extrasData{
+		poc:    &distgit.ExtrasPagurePOC{},
+		owners: &distgit.ExtrasPagureOwnerAlias{},
+		bz:     &distgit.ExtrasPagureBZ{},
+	}
+	var err error
+	if dir != "" {
+		err = errors.Join(
+			loadExtrasFile(dir, "pagure_poc", d.poc),
+			loadExtrasFile(dir, "pagure_owner_alias", d.owners),
+			loadExtrasFile(dir, "pagure_bz", d.bz),
+		)
+		return d, err
+	}
+	e := distgit.NewExtrasClient(args.RootArgs.HTTPClient)
+	if d.poc, err = e.GetPagurePOC(); err != nil {
+		return d, err
+	}
+	if d.owners, err = e.GetPagureOwnerAlias(); err != nil {
+		return d, err
+	}
+	d.bz, err = e.GetPagureBZ()
+	return d, err
+}
+
+func oGenerate() *cobra.Command {
+	var repos []string
+	var extras string
+	var previous string
+	var ftbfs string
+	var outdir string
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate orphans.json and orphans.txt from local repodata",
+		Long: `Generate orphans.json and orphans.txt from local repodata.
+
+Packages whose Fedora point of contact is "orphan" in distgit's pagure_poc.json
+are orphaned.
+Each --repo is searched for packages that depend on the orphans.
+Pass both the binary and the source repositories so build dependencies are
+included.
+
+The status change of packages that were already orphaned is copied from the
+--previous orphans.json.
+It defaults to the existing orphans.json in --output.
+Packages that were orphaned since then get the current time, so run
+"orphans download" first if there is no recent orphans.json.
+
+Packages listed in --ftbfs (one per line) are sorted into
+ftbfs_breaking_deps and ftbfs_not_breaking_deps.`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			if outdir == "" {
+				outdir = args.Dir
+			}
+			var prev *common.Orphans
+			var err error
+			if previous == "" {
+				p := path.Join(outdir, common.OrphansJSON)
+				prev, err = common.LoadOrphans(p)
+				if errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf(
+						"%s not found; pass --previous or run \"orphans download\" first",
+						p,
+					)
+				}
+			} else {
+				prev, err = common.LoadOrphans(previous)
+			}
+			if err != nil {
+				return err
+			}
+
+			data, err := args.getExtras(extras)
+			if err != nil {
+				return err
+			}
+			var failed []string
+			if ftbfs != "" {
+				if failed, err = common.LoadPackageList(ftbfs); err != nil {
+					return err
+				}
+			}
+			pkgs, err := generate.LoadRepos(repos)
+			if err != nil {
+				return err
+			}
+			report, err := generate.Generate(generate.Options{
+				POC:         data.poc,
+				Maintainers: generate.Maintainers(data.owners, data.bz),
+				Packages:    pkgs,
+				FTBFS:       failed,
+				Previous:    prev,
+			})
+			if err != nil {
+				return err
+			}
+			if err = report.WriteFiles(outdir); err != nil {
+				return err
+			}
+			args.recordHistory(report.Orphans)
+			colorToStderrF(
+				color.FgMagenta,
+				"    %d orphans (%d breaking dependencies) written to %s\n",
+				len(report.Orphans.Orphans),
+				len(report.DepTree.Orphans),
+				outdir,
+			)
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(
+		&repos, "repo", nil,
+		"Repository directory containing repodata; can be passed multiple times",
+	)
+	cmd.Flags().StringVar(
+		&extras, "extras", "",
+		"Load pagure_poc.json, pagure_owner_alias.json, and pagure_bz.json from"+
+			" this directory instead of downloading them",
+	)
+	cmd.Flags().StringVar(
+		&previous, "previous", "",
+		"Previous orphans.json to copy status changes from",
+	)
+	cmd.Flags().StringVar(
+		&ftbfs, "ftbfs", "",
+		"File with packages that fail to build from source, one per line",
+	)
+	cmd.Flags().StringVarP(
+		&outdir, "output", "o", "", "Output directory; defaults to --dir",
+	)
+	_ = cmd.MarkFlagRequired("repo")
+	return cmd
+}
@@ -0,0 +1,237 @@
+package cmds
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/history"
+)
+
+func oHistory() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Query previously downloaded orphans data",
+	}
+	cmd.AddCommand(oHistoryList())
+	cmd.AddCommand(oHistoryShow())
+	cmd.AddCommand(oHistoryRecord())
+	return cmd
+}
+
+// parseSnapshotTime parses a snapshot argument.
+// It accepts an RFC 3339 timestamp or a date (which refers to the end of that
+// day in UTC).
+func parseSnapshotTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return t, fmt.Errorf(
+			"invalid snapshot %q: must be \"latest\", a date, or an RFC 3339 timestamp",
+			s,
+		)
+	}
+	return t.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+// findSnapshot returns the newest snapshot at or before the time given in
+// arg.
+// An empty arg or "latest" returns the newest snapshot.
+func findSnapshot(h *history.Store, arg string) (*common.Orphans, error) {
+	var key time.Time
+	var err error
+	if arg == "" || arg == "latest" {
+		key, err = h.Latest()
+	} else {
+		var t time.Time
+		t, err = parseSnapshotTime(arg)
+		if err != nil {
+			return nil, err
+		}
+		key, err = h.FindBefore(t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.Get(key)
+}
+
+func oHistoryList() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List recorded snapshots and how the orphan set changed",
+		Args:    NoArgs,
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			h, err := args.History()
+			if err != nil {
+				return err
+			}
+			defer h.Close()
+			snaps, err := h.List()
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, err = fmt.Fprintln(
+				w,
+				"FINISHED AT\tORPHANS\tCHANGE\tBREAKING DEPS\tORPHANED\tNO LONGER ORPHANED",
+			)
+			if err != nil {
+				return err
+			}
+			var prev *common.Orphans
+			for i, snap := range snaps {
+				cur, err := h.Get(snap.FinishedAt)
+				if err != nil {
+					return err
+				}
+				change, orphaned, removed := "", "", ""
+				if i > 0 {
+					change = fmt.Sprintf("%+d", snap.Orphans-snaps[i-1].Orphans)
+					d, err := common.DiffOrphans(prev, cur, common.OrphansDiffOptions{})
+					if err != nil {
+						return err
+					}
+					orphaned = strings.Join(d.Orphaned, ",")
+					removed = strings.Join(d.Removed, ",")
+				}
+				prev = cur
+				_, err = fmt.Fprintf(
+					w, "%s\t%d\t%s\t%d\t%s\t%s\n",
+					snap.FinishedAt.Format(time.RFC3339),
+					snap.Orphans,
+					change,
+					snap.Breaking,
+					orphaned,
+					removed,
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return w.Flush()
+		},
+	}
+	return cmd
+}
+
+func printSnapshotPackage(o *common.Orphans, pkg string, categories []common.Category) {
+	statusChange := "-"
+	if t, ok := o.StatusChange[pkg]; ok {
+		statusChange = t.Format(time.DateOnly)
+	}
+	names := make([]string, 0, len(categories))
+	for _, c := range categories {
+		names = append(names, string(c))
+	}
+	fmt.Printf("%s\t%s\t%s\n", pkg, statusChange, strings.Join(names, ","))
+}
+
+func oHistoryShow() *cobra.Command {
+	var packages []string
+	asJSON := false
+	cmd := &cobra.Command{
+		Use:   "show [SNAPSHOT]",
+		Short: "Show the newest snapshot at or before SNAPSHOT (date, timestamp, or latest)",
+		Args:  ArgsWrapper(cobra.MaximumNArgs(1)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			h, err := args.History()
+			if err != nil {
+				return err
+			}
+			defer h.Close()
+			arg := ""
+			if len(argv) > 0 {
+				arg = argv[0]
+			}
+			o, err := findSnapshot(h, arg)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return JSONToStdout(o)
+			}
+			colorToStderrF(
+				color.FgMagenta, "Snapshot finished at %s with %d orphans\n",
+				o.FinishedAt.Format(time.RFC3339), len(o.Orphans),
+			)
+			categories := o.PackageCategories()
+			if len(packages) == 0 {
+				for _, pkg := range o.Orphans {
+					printSnapshotPackage(o, pkg, categories[pkg])
+				}
+				return nil
+			}
+			var missing []string
+			for _, pkg := range packages {
+				if !slices.Contains(o.Orphans, pkg) {
+					missing = append(missing, pkg)
+					continue
+				}
+				printSnapshotPackage(o, pkg, categories[pkg])
+				for _, person := range slices.Sorted(maps.Keys(o.AllAffectedPeople)) {
+					if slices.Contains(o.AllAffectedPeople[person], pkg) {
+						direct := slices.Contains(o.AffectedPeople[person], pkg)
+						fmt.Printf("\taffected: %s (direct: %t)\n", person, direct)
+					}
+				}
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf(
+					"not orphaned in this snapshot: %s", strings.Join(missing, ", "),
+				)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().
+		StringSliceVarP(&packages, "package", "p", nil, "Only show these packages")
+	cmd.Flags().BoolVar(&asJSON, "json", asJSON, "Dump the snapshot as orphans JSON")
+	return cmd
+}
+
+func oHistoryRecord() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "record [PATH]",
+		Short: "Record orphans data (defaults to --dir/orphans.json) in the history database",
+		Args:  ArgsWrapper(cobra.MaximumNArgs(1)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			p := path.Join(args.Dir, common.OrphansJSON)
+			if len(argv) > 0 {
+				p = argv[0]
+			}
+			o, err := common.LoadOrphans(p)
+			if err != nil {
+				return err
+			}
+			h, err := args.History()
+			if err != nil {
+				return err
+			}
+			defer h.Close()
+			added, err := h.Add(o)
+			if err != nil {
+				return err
+			}
+			if !added {
+				fmt.Println("Snapshot was already recorded")
+			}
+			return nil
+		},
+	}
+	return cmd
+}
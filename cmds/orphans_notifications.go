@@ -0,0 +1,194 @@
+package cmds
+
+import (
+	"fmt"
+	"maps"
+	"net/mail"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	gomail "github.com/wneessen/go-mail"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/config"
+	"go.gtmx.me/goorphans/fasjson"
+	ourmail "go.gtmx.me/goorphans/mail"
+	"go.gtmx.me/goorphans/notifs"
+)
+
+func writeTemplate(outdir string, td *notifs.UserTemplateData) error {
+	f, err := os.Create(path.Join(outdir, fmt.Sprintf("%s.txt", td.User)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = notifs.UserTemplate.Execute(f, td)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// notificationUsers returns the affected users that should be notified,
+// sorted by name, and the users that were excluded.
+// @groups are never included.
+// If include is not empty, only those users are notified.
+func notificationUsers(
+	o *common.Orphans,
+	include, exclude []string,
+) ([]string, []string) {
+	includeset := mapset.NewThreadUnsafeSet(include...)
+	excludeset := mapset.NewThreadUnsafeSet(exclude...)
+	var users, excluded []string
+	for _, user := range slices.Sorted(maps.Keys(o.AllAffectedPeople)) {
+		if strings.HasPrefix(user, "@") {
+			continue
+		}
+		if (len(include) > 0 && !includeset.Contains(user)) || excludeset.Contains(user) {
+			excluded = append(excluded, user)
+			continue
+		}
+		users = append(users, user)
+	}
+	for _, user := range include {
+		if _, ok := o.AllAffectedPeople[user]; !ok {
+			colorToStderrForce(
+				color.FgYellow,
+				"%s is not affected by any orphans\n",
+				user,
+			)
+		}
+	}
+	return users, excluded
+}
+
+func makeNotificationMsg(
+	config *config.Config,
+	td *notifs.UserTemplateData,
+	email string,
+) (*gomail.Msg, error) {
+	msg := gomail.NewMsg(gomail.WithNoDefaultUserAgent())
+	msg.Subject(fmt.Sprintf(notifs.UserSubjectFmt, td.User))
+	msg.ToMailAddress(&mail.Address{Name: td.User, Address: email})
+	if err := msg.ReplyTo(config.Orphans.ReplyTo); err != nil {
+		return msg, err
+	}
+	if err := msg.SetBodyTextTemplate(notifs.UserTemplate, td); err != nil {
+		return msg, fmt.Errorf("failed to render template for %s: %w", td.User, err)
+	}
+	return msg, nil
+}
+
+// See https://lists.fedoraproject.org/archives/list/devel@lists.fedoraproject.org/message/QD3HH77G2TBXAOTMLN2LMN6W453REEGB/
+func oNotifications() *cobra.Command {
+	outdir := "notifs-rendered"
+	allowStale := false
+	var include, exclude []string
+	cmd := &cobra.Command{
+		Use:     "notifications",
+		Aliases: []string{"notifs"},
+		Short:   "Send individual notifications to affected maintainers",
+		Long: `Send individual notifications to affected maintainers.
+
+Each user that maintains an orphan or a package that depends on one receives a
+summary of the orphans that affect them.
+Members of affected @groups are not notified individually.
+With --dry-run, the notifications are also written to --output-dir.`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, a []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			dryRun := ourmail.DryRunFrom(cmd.Context())
+			o, err := args.OrphansData()
+			if err != nil {
+				return err
+			}
+			if err := args.checkFresh(o, allowStale); err != nil {
+				return err
+			}
+			if dryRun != nil {
+				if err := os.MkdirAll(outdir, 0o755); err != nil {
+					return err
+				}
+			}
+			if err := args.RootArgs.validateSMTP(); err != nil {
+				return err
+			}
+
+			users, excluded := notificationUsers(o, include, exclude)
+			f, err := args.RootArgs.FASCache()
+			if err != nil {
+				return err
+			}
+			var msgs []*gomail.Msg
+			var missing []string
+			for _, user := range users {
+				email, err := f.GetUserEmail(user)
+				if fasjson.IsNotFound(err) {
+					missing = append(missing, user)
+					dryRun.AddUnresolved(user, err)
+					continue
+				} else if err != nil {
+					return fmt.Errorf("failed to get email for %s: %w", user, err)
+				}
+				td := notifs.GetUserTemplateData(o, user)
+				if dryRun != nil {
+					if err = writeTemplate(outdir, td); err != nil {
+						return err
+					}
+				}
+				msg, err := makeNotificationMsg(args.RootArgs.Config, td, email)
+				if err != nil {
+					return err
+				}
+				dryRun.AddRecipients(ourmail.ReasonMaintainer, email)
+				msgs = append(msgs, msg)
+			}
+
+			if len(msgs) > 0 {
+				err = ourmail.SendMsg(cmd.Context(), args.RootArgs.Config, msgs...)
+				if err != nil {
+					return err
+				}
+			}
+
+			if dryRun != nil {
+				colorToStderrForce(
+					color.FgMagenta, "%d notifications written to %s (dry run)\n",
+					len(msgs), outdir,
+				)
+			} else {
+				colorToStderrForce(color.FgMagenta, "%d notifications sent\n", len(msgs))
+			}
+			if len(excluded) > 0 {
+				colorToStderrForce(color.FgMagenta, "%d users excluded\n", len(excluded))
+			}
+			if len(missing) > 0 {
+				colorToStderrForce(
+					color.FgYellow,
+					"%d users not found in FASJSON or without an email address: %s\n",
+					len(missing),
+					strings.Join(missing, ", "),
+				)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(
+		&outdir, "output-dir", "o", outdir,
+		"Directory for --dry-run output",
+	)
+	cmd.Flags().StringSliceVar(
+		&include, "include", nil,
+		"Only notify these users",
+	)
+	cmd.Flags().StringSliceVar(
+		&exclude, "exclude", nil,
+		"Don't notify these users",
+	)
+	cmd.Flags().BoolVar(&allowStale, "allow-stale", allowStale, allowStaleUsage)
+	return cmd
+}
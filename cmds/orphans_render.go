@@ -0,0 +1,84 @@
+package cmds
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/report"
+)
+
+// depTree loads the dependency trees from orphans.txt in args.Dir.
+// It returns nil if orphans.txt does not exist.
+func (args *OrphansArgs) depTree() (*common.DepTree, error) {
+	tree, err := common.LoadDepTree(path.Join(args.Dir, common.OrphansTXT))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return tree, err
+}
+
+// renderReport renders the orphans report from orphans.json using tmpl.
+// The dependency trees are taken from orphans.txt if it exists.
+func (args *OrphansArgs) renderReport(w io.Writer, tmpl *template.Template) error {
+	o, err := args.OrphansData()
+	if err != nil {
+		return err
+	}
+	tree, err := args.depTree()
+	if err != nil {
+		return err
+	}
+	return report.Render(w, tmpl, report.NewData(o, tree))
+}
+
+// reportTemplate returns the template from --template or one of the builtin
+// templates.
+func reportTemplate(name string, markdown bool) (*template.Template, error) {
+	switch {
+	case name != "":
+		return report.ParseTemplate(name)
+	case markdown:
+		return report.MarkdownTemplate, nil
+	default:
+		return report.TXTTemplate, nil
+	}
+}
+
+func oRender() *cobra.Command {
+	out := "-"
+	var tmplName string
+	markdown := false
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Render the human-readable orphans report from orphans.json",
+		Long: `Render the human-readable orphans report from orphans.json.
+
+The dependency trees are taken from orphans.txt if it exists.
+Custom templates receive a report.Data value and can use the
+"orphans_report_node" and "orphans_report_md_node" templates to render
+dependency trees.`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			tmpl, err := reportTemplate(tmplName, markdown)
+			if err != nil {
+				return err
+			}
+			return common.WriteFileFunc(out, func(w io.Writer) error {
+				return args.renderReport(w, tmpl)
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&out, "output", "o", out, "Output file; defaults to stdout")
+	cmd.Flags().StringVarP(
+		&tmplName, "template", "t", "", "Render a custom text/template file",
+	)
+	cmd.Flags().BoolVar(&markdown, "markdown", markdown, "Render the Markdown report")
+	cmd.MarkFlagsMutuallyExclusive("template", "markdown")
+	return cmd
+}
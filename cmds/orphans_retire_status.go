@@ -0,0 +1,133 @@
+package cmds
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/common"
+)
+
+// Retired loads the list of retired packages from args.Dir.
+func (args *OrphansArgs) Retired() (*common.Retired, error) {
+	r, err := common.LoadRetired(path.Join(args.Dir, common.RetiredFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf(
+			"%s does not exist in %s; make sure it's published at the baseurl"+
+				" and run with --download",
+			common.RetiredFile, args.Dir,
+		)
+	}
+	return r, err
+}
+
+func writeRetireStatus(file io.Writer, s *common.RetireStatus) error {
+	w := bufio.NewWriter(file)
+	_, err := fmt.Fprintf(
+		w,
+		"Last retirement run: %s\n\n",
+		s.RetiredAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return err
+	}
+	if s.DataOutdated {
+		_, err = fmt.Fprint(
+			w, "The orphans data is older than the retirement run; refresh it first.\n\n",
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if !s.NeedsAction() {
+		if _, err = fmt.Fprintln(w, "Nothing to do"); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+	sections := []struct {
+		title string
+		pkgs  []string
+	}{
+		{"Retired but still orphaned", s.StillOrphaned},
+		{"Not retired by the last run", s.NotRetired},
+		{"Eligible for the next run", s.Pending},
+	}
+	for _, sec := range sections {
+		if err = writeDiffSection(w, sec.title, sec.pkgs); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func oRetireStatus() *cobra.Command {
+	asJSON := false
+	var toRetire string
+	var exemptions *exemptionFlags
+	cmd := &cobra.Command{
+		Use:   "retire-status",
+		Short: "Compare the orphans data to the list of retired packages",
+		Long: `Compare the orphans data to the list of retired packages.
+
+The retired file at the baseurl lists the packages that retire.py retired in
+the last run.
+This lists retired packages that are still orphaned, packages that were
+eligible for retirement at the time of the run but were not retired, and
+packages that have become eligible since.`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			o, err := args.OrphansData()
+			if err != nil {
+				return err
+			}
+			retired, err := args.Retired()
+			if err != nil {
+				return err
+			}
+			var expected []string
+			if toRetire != "" {
+				pkgs, err := common.LoadPackageList(toRetire)
+				if err != nil {
+					return err
+				}
+				expected = append([]string{}, pkgs...)
+			}
+			options, err := exemptions.options(
+				args, o, common.Weeks(common.RetirementWeeks),
+			)
+			if err != nil {
+				return err
+			}
+			s, err := o.RetireStatus(retired, expected, options, time.Now())
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return JSONToStdout(s)
+			}
+			if err = writeRetireStatus(os.Stdout, s); err != nil {
+				return err
+			}
+			if s.NeedsAction() {
+				colorToStderrF(color.FgYellow, "    Some packages need action\n")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", asJSON, "Print the status as JSON")
+	cmd.Flags().StringVar(
+		&toRetire, "to-retire", "",
+		"File with the packages that were passed to retire.py"+
+			" (defaults to the packages that were eligible at the time of the run)",
+	)
+	exemptions = newExemptionFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,98 @@
+package cmds
+
+import (
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	gomail "github.com/wneessen/go-mail"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/history"
+)
+
+// announceThread is the thread that an announcement continues.
+type announceThread struct {
+	ThreadID   string
+	InReplyTo  string
+	References []string
+}
+
+// setHeaders makes msg a reply to the previous announcement in the thread.
+func (t *announceThread) setHeaders(msg *gomail.Msg) {
+	msg.SetGenHeader(gomail.HeaderInReplyTo, t.InReplyTo)
+	msg.SetGenHeader(gomail.HeaderReferences, strings.Join(t.References, " "))
+}
+
+// announceThread returns the thread that the next announcement should reply
+// to or nil if it should start a new thread.
+// A new thread is started if orphans.thread is disabled, no announcements
+// were recorded yet, or the current thread is older than
+// orphans.thread-weeks.
+func (args *OrphansArgs) announceThread(now time.Time) (*announceThread, error) {
+	if !args.Config.Thread || args.Config.HistoryDB == "" {
+		return nil, nil
+	}
+	h, err := args.History()
+	if err != nil {
+		return nil, err
+	}
+	defer h.Close()
+	last, err := h.LastAnnouncement()
+	if err != nil || last == nil {
+		return nil, err
+	}
+	if args.Config.ThreadWeeks > 0 {
+		start, err := h.ThreadStart(last.ThreadID)
+		if err != nil {
+			return nil, err
+		}
+		if now.Sub(start) > common.Weeks(args.Config.ThreadWeeks) {
+			colorToStderrForce(
+				color.FgMagenta,
+				"The current thread was started on %s; starting a new thread\n",
+				start.Local().Format(time.DateOnly),
+			)
+			return nil, nil
+		}
+	}
+	refs, err := h.References(last.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	return &announceThread{last.ThreadID, last.MessageID, refs}, nil
+}
+
+// recordAnnouncement records the Message-ID of a sent announcement so the
+// next one can reply to it.
+// Failures are only reported as warnings, as the announcement was already
+// sent.
+func (args *OrphansArgs) recordAnnouncement(
+	msg *gomail.Msg,
+	subject string,
+	thread *announceThread,
+) {
+	if args.Config.HistoryDB == "" {
+		return
+	}
+	a := history.Announcement{
+		MessageID: msg.GetMessageID(),
+		Subject:   subject,
+		SentAt:    time.Now(),
+	}
+	a.ThreadID = a.MessageID
+	if thread != nil {
+		a.ThreadID = thread.ThreadID
+	}
+	h, err := args.History()
+	if err == nil {
+		err = h.AddAnnouncement(a)
+		_ = h.Close()
+	}
+	if err != nil {
+		colorToStderrForce(
+			color.FgYellow,
+			"Failed to record the announcement's Message-ID: %v\n",
+			err,
+		)
+	}
+}
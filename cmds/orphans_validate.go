@@ -0,0 +1,91 @@
+package cmds
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/common"
+)
+
+func (args *OrphansArgs) validationReport(
+	checkTxt bool,
+) (*common.ValidationReport, error) {
+	txtPath := ""
+	if checkTxt {
+		txtPath = path.Join(args.Dir, common.OrphansTXT)
+	}
+	return common.ValidateOrphansFiles(path.Join(args.Dir, common.OrphansJSON), txtPath)
+}
+
+func printProblems(report *common.ValidationReport) {
+	for _, p := range report.Problems {
+		clr := color.FgYellow
+		if p.Severity == common.SeverityError {
+			clr = color.FgRed
+		}
+		colorToStderrForce(clr, "%s\n", p)
+	}
+}
+
+// reportErr is like [common.ValidationReport.Err] but doesn't repeat the
+// problems that were already printed.
+func reportErr(report *common.ValidationReport) error {
+	if n := len(report.Errors()); n > 0 {
+		return fmt.Errorf("orphans data failed validation with %d error(s)", n)
+	}
+	return nil
+}
+
+// validate validates the orphans data in args.Dir and prints any problems to
+// stderr.
+// It returns an error if there are any problems with [common.SeverityError].
+func (args *OrphansArgs) validate(checkTxt bool) error {
+	report, err := args.validationReport(checkTxt)
+	if err != nil {
+		return err
+	}
+	printProblems(report)
+	return reportErr(report)
+}
+
+func oValidate() *cobra.Command {
+	asJSON := false
+	checkTxt := true
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check orphans.json and orphans.txt for schema drift and inconsistencies",
+		Args:  NoArgs,
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			// Make sure the data has been downloaded
+			if _, err := args.OrphansData(); err != nil {
+				return err
+			}
+			report, err := args.validationReport(checkTxt)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				if err = JSONToStdout(report); err != nil {
+					return err
+				}
+			} else {
+				printProblems(report)
+			}
+			if err = reportErr(report); err != nil {
+				return err
+			}
+			colorToStderrF(
+				color.FgGreen, "No errors found (%d warnings)\n", len(report.Problems),
+			)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", asJSON, "Print problems as JSON")
+	cmd.Flags().BoolVar(
+		&checkTxt, "txt", checkTxt, "Check that orphans.txt matches orphans.json",
+	)
+	return cmd
+}
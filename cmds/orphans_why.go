@@ -0,0 +1,65 @@
+package cmds
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.gtmx.me/goorphans/common"
+)
+
+func oWhy() *cobra.Command {
+	asJSON := false
+	cmd := &cobra.Command{
+		Use:   "why PACKAGE",
+		Short: "Show the chains of orphaned dependencies that affect PACKAGE",
+		Args:  ArgsWrapper(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			args := cmd.Context().Value(orphansArgsKey).(*OrphansArgs)
+			pkg := argv[0]
+			o, err := args.OrphansData()
+			if err != nil {
+				return err
+			}
+			tree, err := common.LoadDepTree(path.Join(args.Dir, common.OrphansTXT))
+			if err != nil {
+				return err
+			}
+			chains := tree.Why(pkg)
+			if asJSON {
+				return JSONToStdout(chains)
+			}
+			if slices.Contains(o.Orphans, pkg) {
+				fmt.Printf("%s is orphaned\n", pkg)
+			}
+			if len(chains) == 0 {
+				if !slices.Contains(o.Orphans, pkg) {
+					colorToStderrForce(
+						color.FgGreen, "%s does not depend on any orphans\n", pkg,
+					)
+				}
+				return nil
+			}
+			for _, d := range chains {
+				orphan := d.Path[0]
+				since := ""
+				if t, ok := o.StatusChange[orphan]; ok {
+					since = fmt.Sprintf(", orphaned since %s", t.Format(time.DateOnly))
+				}
+				fmt.Printf(
+					"%s (depth %d%s)\n", strings.Join(d.Path, " -> "), d.Depth, since,
+				)
+				for _, req := range d.Requires {
+					fmt.Printf("    %s requires %s\n", req.NEVRA, req.Requires)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", asJSON, "Print the dependency chains as JSON")
+	return cmd
+}
@@ -2,6 +2,7 @@ package cmds
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"github.com/spf13/cobra"
 	"go.gtmx.me/goorphans/config"
 	"go.gtmx.me/goorphans/fasjson"
+	"go.gtmx.me/goorphans/mail"
 )
 
 type argsKeyType struct{ name string }
@@ -20,7 +22,10 @@ var rootArgsKey = &argsKeyType{"root"}
 type RootArgs struct {
 	HTTPClient *http.Client
 	Config     *config.Config
-	fasCache   *fasjson.EmailCacheClient
+	// Print messages instead of sending them.
+	// The command context has a [mail.DryRun].
+	DryRun   bool
+	fasCache *fasjson.EmailCacheClient
 }
 
 func (args *RootArgs) FASCache() (*fasjson.EmailCacheClient, error) {
@@ -34,6 +39,18 @@ func (args *RootArgs) FASCache() (*fasjson.EmailCacheClient, error) {
 	return c, nil
 }
 
+// validateSMTP validates the SMTP config.
+// Only smtp.from is needed for --dry-run.
+func (args *RootArgs) validateSMTP() error {
+	if !args.DryRun {
+		return args.Config.SMTP.Validate()
+	}
+	if args.Config.SMTP.From == "" {
+		return errors.New("missing required configuration key: smtp.from")
+	}
+	return nil
+}
+
 // ArgsWrapper wraps Cobra.PositionalArgs functions and prints usage
 // information if there is an error.
 // This way, we can set SilenceUsage for other errors but still print the usage
@@ -55,6 +72,7 @@ func NewRootCmd() *cobra.Command {
 	var configPath string
 	var ttl float64
 	var dbPath string
+	dryRunLines := 40
 	cobra.EnableTraverseRunHooks = true
 	args := RootArgs{}
 	rootCmd := &cobra.Command{
@@ -89,7 +107,11 @@ func NewRootCmd() *cobra.Command {
 			// 	return err
 			// }
 			args.HTTPClient = &http.Client{}
-			cmd.SetContext(context.WithValue(cmd.Context(), rootArgsKey, &args))
+			ctx := context.WithValue(cmd.Context(), rootArgsKey, &args)
+			if args.DryRun {
+				ctx = mail.WithDryRun(ctx, &mail.DryRun{Lines: dryRunLines})
+			}
+			cmd.SetContext(ctx)
 			return nil
 		},
 		SilenceUsage: true,
@@ -106,11 +128,22 @@ func NewRootCmd() *cobra.Command {
 			&dbPath, "fasjson-db", "",
 			"Path to cache database. Defaults to $XDG_CACHE_HOME/goorphans/fasjson.db",
 		)
+	rootCmd.PersistentFlags().
+		BoolVarP(
+			&args.DryRun, "dry-run", "n", false,
+			"Print the messages and a report of their recipients instead of sending them",
+		)
+	rootCmd.PersistentFlags().
+		IntVar(
+			&dryRunLines, "dry-run-lines", dryRunLines,
+			"Number of body lines to print for each message with --dry-run (0 for all)",
+		)
 	rootCmd.AddCommand(newOrphansCommand())
 	rootCmd.AddCommand(newFas2emailCommand())
 	rootCmd.AddCommand(NewDistgitCmd())
 	rootCmd.AddCommand(newDumpConfigCmd())
 	rootCmd.AddCommand(newNagsCmd())
+	rootCmd.AddCommand(newMailCmd())
 	// rootCmd.AddCommand(newDocsGenCmd())
 	return rootCmd
 }
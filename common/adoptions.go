@@ -0,0 +1,15 @@
+package common
+
+import "time"
+
+// Adoption is an orphaned package that was adopted instead of being retired.
+type Adoption struct {
+	Package string `json:"package"`
+	// The package's new point of contact in pagure_poc.json
+	Admin string `json:"admin"`
+	// When the package was orphaned, if known
+	OrphanedAt *time.Time `json:"orphaned_at"`
+	// FinishedAt of the first orphans data where the package was no longer
+	// listed
+	DetectedAt time.Time `json:"detected_at"`
+}
@@ -0,0 +1,255 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	dependingOnRe = regexp.MustCompile(
+		`^Depending on: (\S+) \((\d+)\)(?:, status change: (\S+))?`,
+	)
+	dependentRe = regexp.MustCompile(`^(\t+)(\S+) \(maintained by: (.*)\)\s*$`)
+	requiresRe  = regexp.MustCompile(`^\t+(\S+) requires (.+?)\s*$`)
+)
+
+// DepTree is the dependency information from the "Depending on" section of
+// orphans.txt.
+type DepTree struct {
+	// Orphan package name -> the packages that depend on it
+	Orphans map[string]*OrphanDeps `json:"orphans"`
+}
+
+// OrphanDeps lists the packages that break when an orphan is retired.
+type OrphanDeps struct {
+	Orphan string `json:"orphan"`
+	// The number of dependents stated in the report
+	Count        int          `json:"count"`
+	StatusChange string       `json:"status_change,omitempty"`
+	Dependents   []*Dependent `json:"dependents"`
+}
+
+// Dependent is a package that depends on an orphan directly or through other
+// dependents.
+type Dependent struct {
+	Package     string        `json:"package"`
+	Maintainers []string      `json:"maintainers"`
+	Requires    []Requirement `json:"requires"`
+	// 1 for packages that depend on the orphan directly
+	Depth int `json:"depth"`
+	// The chain of packages from the orphan to this package, inclusive
+	Path []string `json:"path"`
+}
+
+// Requirement is a single "NEVRA requires DEP" line.
+type Requirement struct {
+	NEVRA    string `json:"nevra"`
+	Requires string `json:"requires"`
+}
+
+// nevraName returns the name part of a name-[epoch:]version-release.arch
+// string.
+func nevraName(nevra string) string {
+	i := strings.LastIndex(nevra, "-")
+	if i <= 0 {
+		return nevra
+	}
+	j := strings.LastIndex(nevra[:i], "-")
+	if j <= 0 {
+		return nevra[:i]
+	}
+	return nevra[:j]
+}
+
+// LoadDepTree parses the orphans.txt file at path.
+func LoadDepTree(path string) (*DepTree, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load orphans.txt: %w", err)
+	}
+	defer f.Close()
+	t, err := ParseDepTree(f)
+	if err != nil {
+		return t, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// ParseDepTree parses the dependency trees from an orphans.txt report.
+//
+// Dependents that are indented by more than one tab are treated as children
+// of the previous dependent with one less tab.
+// The report generated by find_unblocked_orphans.py lists all dependents at
+// the same level, so their parents are inferred by matching each requirement
+// against the binary package names of the other dependents in the block.
+// Requirements that don't match another dependent are assumed to be provided
+// by the orphan.
+func ParseDepTree(r io.Reader) (*DepTree, error) {
+	t := &DepTree{Orphans: map[string]*OrphanDeps{}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var current *OrphanDeps
+	var last *Dependent
+	// Parent of each dependent when it's given explicitly by indentation
+	var parents map[*Dependent]*Dependent
+	var stack []*Dependent
+	finish := func() {
+		if current != nil {
+			current.resolve(parents)
+		}
+		current, last, stack = nil, nil, nil
+	}
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := scanner.Text()
+		if m := dependingOnRe.FindStringSubmatch(line); m != nil {
+			finish()
+			count, _ := strconv.Atoi(m[2])
+			current = &OrphanDeps{Orphan: m[1], Count: count, StatusChange: m[3]}
+			t.Orphans[current.Orphan] = current
+			parents = map[*Dependent]*Dependent{}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "\t") {
+			finish()
+			continue
+		}
+		if m := dependentRe.FindStringSubmatch(line); m != nil {
+			level := len(m[1])
+			last = &Dependent{Package: m[2], Maintainers: splitMaintainers(m[3])}
+			current.Dependents = append(current.Dependents, last)
+			if level > len(stack)+1 {
+				return t, fmt.Errorf("line %d: unexpected indentation", lineno)
+			}
+			stack = append(stack[:level-1], last)
+			if level > 1 {
+				parents[last] = stack[level-2]
+			}
+			continue
+		}
+		if m := requiresRe.FindStringSubmatch(line); m != nil {
+			if last == nil {
+				return t, fmt.Errorf("line %d: requirement without a package", lineno)
+			}
+			last.Requires = append(last.Requires, Requirement{m[1], m[2]})
+		}
+		// Ignore any other indented lines
+	}
+	finish()
+	return t, scanner.Err()
+}
+
+func splitMaintainers(s string) []string {
+	var r []string
+	for m := range strings.SplitSeq(s, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			r = append(r, m)
+		}
+	}
+	return r
+}
+
+// resolve computes the Depth and Path of each dependent.
+func (o *OrphanDeps) resolve(explicit map[*Dependent]*Dependent) {
+	// binary package name -> dependent that builds it
+	binaries := map[string]*Dependent{}
+	for _, d := range o.Dependents {
+		for _, req := range d.Requires {
+			name := nevraName(req.NEVRA)
+			if !strings.HasSuffix(req.NEVRA, ".src") {
+				binaries[name] = d
+			}
+		}
+	}
+	parents := map[*Dependent][]*Dependent{}
+	for _, d := range o.Dependents {
+		if p, ok := explicit[d]; ok {
+			parents[d] = []*Dependent{p}
+			continue
+		}
+		for _, req := range d.Requires {
+			dep, _, _ := strings.Cut(req.Requires, " ")
+			if p, ok := binaries[dep]; ok && p != d {
+				parents[d] = append(parents[d], p)
+			} else {
+				// Provided by the orphan
+				parents[d] = append(parents[d], nil)
+			}
+		}
+		if len(parents[d]) == 0 {
+			parents[d] = []*Dependent{nil}
+		}
+	}
+	// Breadth-first search from the orphan so each dependent gets the
+	// shortest path.
+	done := map[*Dependent]bool{}
+	frontier := []*Dependent{nil}
+	for depth := 1; len(frontier) > 0; depth++ {
+		var next []*Dependent
+		for _, d := range o.Dependents {
+			if done[d] {
+				continue
+			}
+			for _, p := range parents[d] {
+				if !slices.Contains(frontier, p) {
+					continue
+				}
+				d.Depth = depth
+				if p == nil {
+					d.Path = []string{o.Orphan, d.Package}
+				} else {
+					d.Path = append(append([]string{}, p.Path...), d.Package)
+				}
+				done[d] = true
+				next = append(next, d)
+				break
+			}
+		}
+		frontier = next
+	}
+	// Fall back to a direct dependency if the requirements form a cycle
+	// that doesn't lead back to the orphan.
+	for _, d := range o.Dependents {
+		if !done[d] {
+			d.Depth = 1
+			d.Path = []string{o.Orphan, d.Package}
+		}
+	}
+}
+
+func sortDependents(ds []*Dependent) {
+	slices.SortFunc(ds, func(a, b *Dependent) int {
+		if a.Depth != b.Depth {
+			return a.Depth - b.Depth
+		}
+		return slices.Compare(a.Path, b.Path)
+	})
+}
+
+// Why returns every dependent entry for pkg across all orphans, sorted by
+// depth.
+func (t *DepTree) Why(pkg string) []*Dependent {
+	var r []*Dependent
+	for _, o := range t.Orphans {
+		for _, d := range o.Dependents {
+			if d.Package == pkg {
+				r = append(r, d)
+			}
+		}
+	}
+	sortDependents(r)
+	return r
+}
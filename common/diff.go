@@ -0,0 +1,123 @@
+package common
+
+import (
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// OrphansDiff describes the changes between two [Orphans] datasets.
+type OrphansDiff struct {
+	OldFinishedAt *time.Time `json:"old_finished_at"`
+	NewFinishedAt *time.Time `json:"new_finished_at"`
+	// Packages that were not orphaned in the old dataset
+	Orphaned []string `json:"orphaned"`
+	// Packages that are no longer orphaned and were not retired
+	Adopted []string `json:"adopted"`
+	// Packages that are no longer orphaned because they were retired
+	Retired []string `json:"retired"`
+	// Packages that are no longer orphaned when retirement was not checked
+	Removed []string `json:"removed"`
+	// Orphans that now break dependencies
+	ToBreakingDeps []string `json:"to_breaking_deps"`
+	// Orphans that no longer break dependencies
+	ToNotBreakingDeps []string `json:"to_not_breaking_deps"`
+	// Changes to [Orphans.AffectedPeople] by user or @group
+	AffectedPeople map[string]*AffectedPeopleDiff `json:"affected_people"`
+}
+
+type AffectedPeopleDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type OrphansDiffOptions struct {
+	// IsRetired is used to sort packages that are no longer orphaned into
+	// Adopted and Retired.
+	// All such packages are listed in Removed if it is nil.
+	IsRetired func(pkg string) (bool, error)
+}
+
+// BreakingDeps returns the orphans that break dependencies, including stale
+// ones.
+func (o *Orphans) BreakingDeps() mapset.Set[string] {
+	s := mapset.NewThreadUnsafeSet(o.OrphansBreakingDeps...)
+	s.Append(o.OrphansBreakingDepsStale...)
+	return s
+}
+
+// NotBreakingDeps returns the orphans that don't break dependencies,
+// including stale ones.
+func (o *Orphans) NotBreakingDeps() mapset.Set[string] {
+	s := mapset.NewThreadUnsafeSet(o.OrphansNotBreakingDeps...)
+	s.Append(o.OrphansNotBreakingDepsStale...)
+	return s
+}
+
+// DiffOrphans compares the prev and cur datasets.
+func DiffOrphans(prev, cur *Orphans, options OrphansDiffOptions) (*OrphansDiff, error) {
+	d := &OrphansDiff{
+		OldFinishedAt:  prev.FinishedAt,
+		NewFinishedAt:  cur.FinishedAt,
+		AffectedPeople: map[string]*AffectedPeopleDiff{},
+	}
+	oldset := mapset.NewThreadUnsafeSet(prev.Orphans...)
+	newset := mapset.NewThreadUnsafeSet(cur.Orphans...)
+
+	d.Orphaned = mapset.Sorted(newset.Difference(oldset))
+	for _, pkg := range mapset.Sorted(oldset.Difference(newset)) {
+		if options.IsRetired == nil {
+			d.Removed = append(d.Removed, pkg)
+			continue
+		}
+		retired, err := options.IsRetired(pkg)
+		if err != nil {
+			return d, err
+		}
+		if retired {
+			d.Retired = append(d.Retired, pkg)
+		} else {
+			d.Adopted = append(d.Adopted, pkg)
+		}
+	}
+
+	both := oldset.Intersect(newset)
+	oldBreaking, newBreaking := prev.BreakingDeps(), cur.BreakingDeps()
+	oldNotBreaking, newNotBreaking := prev.NotBreakingDeps(), cur.NotBreakingDeps()
+	for _, pkg := range mapset.Sorted(both) {
+		switch {
+		case oldNotBreaking.Contains(pkg) && newBreaking.Contains(pkg):
+			d.ToBreakingDeps = append(d.ToBreakingDeps, pkg)
+		case oldBreaking.Contains(pkg) && newNotBreaking.Contains(pkg):
+			d.ToNotBreakingDeps = append(d.ToNotBreakingDeps, pkg)
+		}
+	}
+
+	people := mapset.NewThreadUnsafeSet[string]()
+	for person := range prev.AffectedPeople {
+		people.Add(person)
+	}
+	for person := range cur.AffectedPeople {
+		people.Add(person)
+	}
+	for person := range mapset.Elements(people) {
+		oldpkgs := mapset.NewThreadUnsafeSet(prev.AffectedPeople[person]...)
+		newpkgs := mapset.NewThreadUnsafeSet(cur.AffectedPeople[person]...)
+		if oldpkgs.Equal(newpkgs) {
+			continue
+		}
+		d.AffectedPeople[person] = &AffectedPeopleDiff{
+			Added:   mapset.Sorted(newpkgs.Difference(oldpkgs)),
+			Removed: mapset.Sorted(oldpkgs.Difference(newpkgs)),
+		}
+	}
+	return d, nil
+}
+
+// Empty returns whether the diff contains no changes.
+func (d *OrphansDiff) Empty() bool {
+	return len(d.Orphaned) == 0 &&
+		len(d.Adopted)+len(d.Retired)+len(d.Removed) == 0 &&
+		len(d.ToBreakingDeps)+len(d.ToNotBreakingDeps) == 0 &&
+		len(d.AffectedPeople) == 0
+}
@@ -0,0 +1,275 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// FlagDate is the flag date of the builtin Golang exemption policy.
+var FlagDate = time.Date(2025, time.November, 3, 0, 0, 0, 0, time.UTC)
+
+// GolangExemptionPolicyName is the name of the builtin exemption policy for
+// [Orphans.GolangExemptions].
+const GolangExemptionPolicyName = "golang"
+
+// ExemptionMode controls how the packages covered by an [ExemptionPolicy]
+// are treated.
+type ExemptionMode int
+
+const (
+	// Exclude exempt packages and fail if the policy lists no packages
+	ExemptionModeMust ExemptionMode = iota
+	// Exclude exempt packages
+	ExemptionModeOptional
+	// Count the grace period of exempt packages from the policy's flag date
+	// and use the policy's grace period
+	ExemptionModeFlagDate
+	// Treat exempt packages like any other package
+	ExemptionModeIgnore
+	// Only include exempt packages
+	ExemptionModeOnly
+)
+
+var ToExemptionMode = map[string]ExemptionMode{
+	"must":     ExemptionModeMust,
+	"optional": ExemptionModeOptional,
+	"flagdate": ExemptionModeFlagDate,
+	"ignore":   ExemptionModeIgnore,
+	"only":     ExemptionModeOnly,
+}
+
+var FromExemptionMode = map[ExemptionMode]string{
+	ExemptionModeMust:     "must",
+	ExemptionModeOptional: "optional",
+	ExemptionModeFlagDate: "flagdate",
+	ExemptionModeIgnore:   "ignore",
+	ExemptionModeOnly:     "only",
+}
+
+func (m ExemptionMode) String() string {
+	return FromExemptionMode[m]
+}
+
+func (m ExemptionMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *ExemptionMode) UnmarshalText(text []byte) error {
+	s := string(text)
+	var ok bool
+	*m, ok = ToExemptionMode[s]
+	if !ok {
+		return fmt.Errorf("invalid exemption mode: %q", s)
+	}
+	return nil
+}
+
+// GolangExemption is the mode of the builtin Golang exemption policy.
+type GolangExemption = ExemptionMode
+
+const (
+	GolangExemptionMust     = ExemptionModeMust
+	GolangExemptionOptional = ExemptionModeOptional
+	GolangExemptionFlagDate = ExemptionModeFlagDate
+	GolangExemptionIgnore   = ExemptionModeIgnore
+	GolangExemptionOnly     = ExemptionModeOnly
+)
+
+var (
+	ToGolangExemption   = ToExemptionMode
+	FromGolangExemption = FromExemptionMode
+)
+
+// ExemptionPolicy gives a set of orphaned packages special treatment, e.g.,
+// a longer grace period for a SIG that's working through a backlog.
+type ExemptionPolicy struct {
+	Name     string
+	Packages []string
+	Mode     ExemptionMode
+	// The grace period of exempt packages starts no earlier than FlagDate.
+	// Only used with [ExemptionModeFlagDate]; the zero value disables it.
+	FlagDate time.Time
+	// The grace period of exempt packages.
+	// Only used with [ExemptionModeFlagDate]; 0 uses the default grace period.
+	Grace time.Duration
+}
+
+// GolangExemptionPolicy returns the builtin policy for
+// [Orphans.GolangExemptions].
+func (o *Orphans) GolangExemptionPolicy(mode ExemptionMode) ExemptionPolicy {
+	return ExemptionPolicy{
+		Name:     GolangExemptionPolicyName,
+		Packages: o.GolangExemptions,
+		Mode:     mode,
+		FlagDate: FlagDate,
+	}
+}
+
+// ExemptionField returns the packages in an "*_exemptions" field of
+// orphans.json.
+func (o *Orphans) ExemptionField(field string) ([]string, bool) {
+	if field == "golang_exemptions" {
+		return o.GolangExemptions, o.GolangExemptions != nil
+	}
+	pkgs, ok := o.Exemptions[field]
+	return pkgs, ok
+}
+
+// isExemptionField returns whether key is an "*_exemptions" field of
+// orphans.json.
+func isExemptionField(key string) bool {
+	return strings.HasSuffix(key, "_exemptions")
+}
+
+// UnmarshalJSON decodes orphans.json and collects any "*_exemptions" fields
+// other than golang_exemptions in o.Exemptions.
+func (o *Orphans) UnmarshalJSON(data []byte) error {
+	type plain Orphans
+	if err := json.Unmarshal(data, (*plain)(o)); err != nil {
+		return err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for key, value := range raw {
+		if !isExemptionField(key) || key == "golang_exemptions" {
+			continue
+		}
+		var pkgs []string
+		dec := json.NewDecoder(bytes.NewReader(value))
+		if err := dec.Decode(&pkgs); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", key, err)
+		}
+		if o.Exemptions == nil {
+			o.Exemptions = map[string][]string{}
+		}
+		o.Exemptions[key] = pkgs
+	}
+	return nil
+}
+
+// MarshalJSON encodes o with the fields in o.Exemptions added back as
+// "*_exemptions" fields, so they survive a round trip through
+// [Orphans.UnmarshalJSON].
+func (o *Orphans) MarshalJSON() ([]byte, error) {
+	type plain Orphans
+	data, err := json.Marshal((*plain)(o))
+	if err != nil || len(o.Exemptions) == 0 {
+		return data, err
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for _, key := range slices.Sorted(maps.Keys(o.Exemptions)) {
+		if !isExemptionField(key) || key == "golang_exemptions" {
+			continue
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.Exemptions[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(',')
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// exemptionRules are the exemption policies that apply to an [Orphans]
+// dataset.
+type exemptionRules struct {
+	policies []ExemptionPolicy
+	sets     []mapset.Set[string]
+}
+
+// exemptionRules combines the builtin Golang policy with
+// options.Exemptions.
+// A policy in options.Exemptions named [GolangExemptionPolicyName] replaces
+// the builtin one.
+func (o *Orphans) exemptionRules(options OrphanedFilterOptions) (*exemptionRules, error) {
+	policies := options.Exemptions
+	if !slices.ContainsFunc(policies, func(p ExemptionPolicy) bool {
+		return p.Name == GolangExemptionPolicyName
+	}) {
+		policies = append(
+			[]ExemptionPolicy{o.GolangExemptionPolicy(options.GolangExemption)},
+			policies...,
+		)
+	}
+	r := &exemptionRules{policies: policies}
+	for _, p := range policies {
+		if p.Mode == ExemptionModeMust && len(p.Packages) == 0 {
+			return nil, fmt.Errorf(
+				"exemption policy %q is required (mode must) but lists no packages",
+				p.Name,
+			)
+		}
+		r.sets = append(r.sets, mapset.NewThreadUnsafeSet(p.Packages...))
+	}
+	return r, nil
+}
+
+// skip returns whether pkg is excluded by the policies.
+func (r *exemptionRules) skip(pkg string) bool {
+	only, inOnly := false, false
+	for i, p := range r.policies {
+		exempt := r.sets[i].Contains(pkg)
+		switch p.Mode {
+		case ExemptionModeMust, ExemptionModeOptional:
+			if exempt {
+				return true
+			}
+		case ExemptionModeOnly:
+			only = true
+			inOnly = inOnly || exempt
+		}
+	}
+	return only && !inOnly
+}
+
+// retireAt returns when pkg becomes eligible for retirement after the grace
+// period.
+// The flagdate policies that cover pkg can delay the start of the grace period
+// and replace its length with their own, which may be shorter.
+// If several of them set a grace period, the longest one is used.
+func (r *exemptionRules) retireAt(o *Orphans, pkg string, grace time.Duration) time.Time {
+	start := o.StatusChange[pkg]
+	var policyGrace time.Duration
+	for i, p := range r.policies {
+		if p.Mode != ExemptionModeFlagDate || !r.sets[i].Contains(pkg) {
+			continue
+		}
+		if !p.FlagDate.IsZero() && start.Before(p.FlagDate) {
+			start = p.FlagDate
+		}
+		policyGrace = max(policyGrace, p.Grace)
+	}
+	if policyGrace > 0 {
+		grace = policyGrace
+	}
+	return start.Add(grace)
+}
+
+// names returns the names of the policies that cover pkg.
+func (r *exemptionRules) names(pkg string) []string {
+	var names []string
+	for i, p := range r.policies {
+		if r.sets[i].Contains(pkg) {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
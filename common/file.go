@@ -3,6 +3,7 @@ package common
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path"
 )
@@ -60,3 +61,28 @@ func WriteFileLines(name string, lines []string) error {
 	}
 	return nil
 }
+
+// WriteFileFunc calls f with a buffered writer for name.
+// Like [WriteFileLines], "-" writes to stdout.
+func WriteFileFunc(name string, f func(w io.Writer) error) error {
+	if name == "-" {
+		writer := bufio.NewWriter(os.Stdout)
+		if err := f(writer); err != nil {
+			return err
+		}
+		return writer.Flush()
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	if err = f(writer); err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -9,6 +9,7 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"path/filepath"
 )
 
 type StatusCodeError struct {
@@ -69,24 +70,92 @@ func GetJSON(client *http.Client, dest any, path *url.URL) error {
 }
 
 func DownloadFile(client *http.Client, url string, path string) error {
-	resp, err := client.Get(url)
+	tmp, _, err := DownloadToTemp(client, url, path, nil)
 	if err != nil {
 		return err
 	}
-	if err := CheckStatusCode(resp); err != nil {
-		return err
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to move %q into place: %v", path, err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
+
+// DownloadValidators holds the HTTP cache validators of a downloaded file.
+type DownloadValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
 
-	file, err := os.Create(path)
+// DownloadToTemp downloads url to a temporary file in the same directory as
+// path so it can be renamed into place once the caller has checked it.
+// If validators is not nil, the request is conditional and tmp is empty when
+// the server reports that the file has not been modified.
+// The returned validators are those sent by the server.
+// The temporary file's modification time is set from Last-Modified.
+func DownloadToTemp(
+	client *http.Client,
+	url string,
+	path string,
+	validators *DownloadValidators,
+) (tmp string, newValidators *DownloadValidators, err error) {
+	log.Printf("GET %s", url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to set up request: %w", err)
+	}
+	req.Header.Set("User-Agent", "go.gtmx.me/goorphans")
+	if validators != nil {
+		if validators.ETag != "" {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
+		if validators.LastModified != "" {
+			req.Header.Set("If-Modified-Since", validators.LastModified)
+		}
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to create %q: %v", path, err)
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if validators != nil && resp.StatusCode == http.StatusNotModified {
+		return "", validators, nil
+	}
+	if err := CheckStatusCode(resp); err != nil {
+		return "", nil, err
 	}
-	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
 	if err != nil {
-		return fmt.Errorf("failed to write to %q: %v", path, err)
+		return "", nil, fmt.Errorf(
+			"failed to create temporary file for %q: %v",
+			path,
+			err,
+		)
 	}
-	return nil
+	tmp = file.Name()
+	// CreateTemp uses 0o600, but the downloaded data isn't private.
+	err = file.Chmod(0o644)
+	if err == nil {
+		_, err = io.Copy(file, resp.Body)
+	}
+	if err == nil {
+		err = file.Close()
+	} else {
+		_ = file.Close()
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return "", nil, fmt.Errorf("failed to write to %q: %v", path, err)
+	}
+	newValidators = &DownloadValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	// Keep the server's modification time so it can be used to tell when
+	// the file was published.
+	if t, err := http.ParseTime(newValidators.LastModified); err == nil {
+		_ = os.Chtimes(tmp, t, t)
+	}
+	return tmp, newValidators, nil
 }
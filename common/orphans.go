@@ -2,8 +2,12 @@ package common
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
@@ -17,51 +21,8 @@ const (
 )
 const week time.Duration = time.Hour * 24 * 7
 
-var FlagDate = time.Date(2025, time.November, 3, 0, 0, 0, 0, time.UTC)
-
-type GolangExemption int
-
-const (
-	GolangExemptionMust GolangExemption = iota
-	GolangExemptionOptional
-	GolangExemptionFlagDate
-	GolangExemptionIgnore
-	GolangExemptionOnly
-)
-
-var ToGolangExemption = map[string]GolangExemption{
-	"must":     GolangExemptionMust,
-	"optional": GolangExemptionOptional,
-	"flagdate": GolangExemptionFlagDate,
-	"ignore":   GolangExemptionIgnore,
-	"only":     GolangExemptionOnly,
-}
-
-var FromGolangExemption = map[GolangExemption]string{
-	GolangExemptionMust:     "must",
-	GolangExemptionOptional: "optional",
-	GolangExemptionFlagDate: "flagdate",
-	GolangExemptionIgnore:   "ignore",
-	GolangExemptionOnly:     "only",
-}
-
-func (ge GolangExemption) String() string {
-	return FromGolangExemption[ge]
-}
-
-func (ge GolangExemption) MarshalText() ([]byte, error) {
-	return []byte(ge.String()), nil
-}
-
-func (ge *GolangExemption) UnmarshalText(text []byte) error {
-	s := string(text)
-	var ok bool
-	*ge, ok = ToGolangExemption[s]
-	if !ok {
-		return fmt.Errorf("invalid GolangExemption value: %q", s)
-	}
-	return nil
-}
+// RetirementWeeks is the number of weeks after which orphans are retired.
+const RetirementWeeks = 6
 
 // Weeks returns weeks represented as a [time.Duration]
 func Weeks(weeks int) time.Duration {
@@ -84,6 +45,8 @@ type Orphans struct {
 	StatusChange                map[string]time.Time `json:"status_change"`
 	StartedAt                   *time.Time           `json:"started_at,omitempty"`
 	FinishedAt                  *time.Time           `json:"finished_at,omitempty"`
+	// Other "*_exemptions" fields by name
+	Exemptions map[string][]string `json:"-"`
 }
 
 func LoadOrphans(path string) (*Orphans, error) {
@@ -100,39 +63,319 @@ func LoadOrphans(path string) (*Orphans, error) {
 	return &orphans, nil
 }
 
+var (
+	ErrIncompleteRun     = errors.New("orphans data has started_at but no finished_at")
+	ErrMissingFinishedAt = errors.New("finished_at was not included in the orphans data")
+)
+
+// StaleDataError is returned by [Orphans.CheckFresh] when the data is too old.
+type StaleDataError struct {
+	Age    time.Duration
+	MaxAge time.Duration
+}
+
+func (e *StaleDataError) Error() string {
+	return fmt.Sprintf(
+		"orphans data is %s old which is older than the maximum of %s",
+		e.Age.Round(time.Minute), e.MaxAge,
+	)
+}
+
+// CheckFresh returns an error if o is from an incomplete run or if it
+// finished more than maxAge before now.
+// A maxAge of 0 only checks that the run is complete.
+func (o *Orphans) CheckFresh(maxAge time.Duration, now time.Time) error {
+	if o.FinishedAt == nil {
+		if o.StartedAt != nil {
+			return ErrIncompleteRun
+		}
+		return ErrMissingFinishedAt
+	}
+	if age := now.Sub(*o.FinishedAt); maxAge != 0 && age > maxAge {
+		return &StaleDataError{age, maxAge}
+	}
+	return nil
+}
+
 type OrphanedFilterOptions struct {
-	Duration        time.Duration
+	Duration time.Duration
+	// Mode of the builtin Golang exemption policy
 	GolangExemption GolangExemption
+	// Additional exemption policies
+	Exemptions []ExemptionPolicy
+	// Only include orphans that are listed in at least one of these
+	// categories. All orphans are included if this is empty.
+	Categories []Category
+	// Exclude orphans that are listed in any of these categories
+	ExcludeCategories []Category
 }
 
 func (o *Orphans) OrphanedFilter(options OrphanedFilterOptions) (r []string, err error) {
 	now := time.Now().UTC()
-	if options.GolangExemption == GolangExemptionMust && len(o.GolangExemptions) == 0 {
-		return r, fmt.Errorf("GolangExemptionMust but no exemptions were listed")
+	rules, err := o.exemptionRules(options)
+	if err != nil {
+		return r, err
+	}
+	selected, err := o.categoryFilter(options)
+	if err != nil {
+		return r, err
 	}
-	exemptions := mapset.NewThreadUnsafeSet(o.GolangExemptions...)
 	for _, p := range o.Orphans {
-		exemptionContains := exemptions.Contains(p)
-		if options.Duration != 0 {
-			t := o.StatusChange[p]
-			if options.GolangExemption == GolangExemptionFlagDate && exemptionContains &&
-				t.Before(FlagDate) {
-				t = FlagDate
-			}
-			elapsed := now.Sub(t)
-			if elapsed < options.Duration {
-				continue
-			}
+		if options.Duration != 0 && now.Before(rules.retireAt(o, p, options.Duration)) {
+			continue
 		}
-		if exemptionContains {
-			if options.GolangExemption == GolangExemptionMust ||
-				options.GolangExemption == GolangExemptionOptional {
-				continue
-			}
-		} else if options.GolangExemption == GolangExemptionOnly {
+		if rules.skip(p) || !selected(p) {
 			continue
 		}
 		r = append(r, p)
 	}
 	return r, nil
 }
+
+// Deadline is the date when an orphaned package becomes eligible for
+// retirement.
+type Deadline struct {
+	Package string `json:"package"`
+	// nil if the package is missing from [Orphans.StatusChange]
+	StatusChange    *time.Time `json:"status_change"`
+	RetireAt        time.Time  `json:"retire_at"`
+	GolangExemption bool       `json:"golang_exemption"`
+	// Names of the exemption policies that cover the package
+	Exemptions []string `json:"exemptions"`
+}
+
+// Remaining returns the time left until d.RetireAt.
+// It is negative once the package is eligible for retirement.
+func (d *Deadline) Remaining(now time.Time) time.Duration {
+	return d.RetireAt.Sub(now)
+}
+
+// Eligible returns whether the package may be retired at now.
+func (d *Deadline) Eligible(now time.Time) bool {
+	return !now.Before(d.RetireAt)
+}
+
+// DaysRemaining returns the number of whole days until d.RetireAt, rounded up.
+// It is zero or negative once the package is eligible for retirement.
+func (d *Deadline) DaysRemaining(now time.Time) int {
+	return int(math.Ceil(d.Remaining(now).Hours() / 24))
+}
+
+// Deadlines computes the retirement date of each orphan that is not excluded
+// by the exemption policies or the category filters in options.
+// options.Duration is used as the grace period.
+// Results are sorted by retirement date.
+func (o *Orphans) Deadlines(options OrphanedFilterOptions) (r []Deadline, err error) {
+	rules, err := o.exemptionRules(options)
+	if err != nil {
+		return r, err
+	}
+	selected, err := o.categoryFilter(options)
+	if err != nil {
+		return r, err
+	}
+	for _, p := range o.Orphans {
+		if rules.skip(p) || !selected(p) {
+			continue
+		}
+		exemptions := rules.names(p)
+		d := Deadline{
+			Package:         p,
+			RetireAt:        rules.retireAt(o, p, options.Duration),
+			GolangExemption: slices.Contains(exemptions, GolangExemptionPolicyName),
+			Exemptions:      exemptions,
+		}
+		if t, ok := o.StatusChange[p]; ok {
+			d.StatusChange = &t
+		}
+		r = append(r, d)
+	}
+	slices.SortStableFunc(r, func(a, b Deadline) int {
+		if c := a.RetireAt.Compare(b.RetireAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Package, b.Package)
+	})
+	return r, nil
+}
+
+// Category names one of the package lists in [Orphans] that classify orphaned
+// and FTBFS packages.
+// The values match the JSON keys in orphans.json.
+type Category string
+
+const (
+	CategoryOrphansBreakingDeps         Category = "orphans_breaking_deps"
+	CategoryOrphansBreakingDepsStale    Category = "orphans_breaking_deps_stale"
+	CategoryOrphansNotBreakingDeps      Category = "orphans_not_breaking_deps"
+	CategoryOrphansNotBreakingDepsStale Category = "orphans_not_breaking_deps_stale"
+	CategoryFtbfsBreakingDeps           Category = "ftbfs_breaking_deps"
+	CategoryFtbfsNotBreakingDeps        Category = "ftbfs_not_breaking_deps"
+)
+
+// Categories lists every [Category] in the order they appear in orphans.json.
+var Categories = []Category{
+	CategoryFtbfsBreakingDeps,
+	CategoryFtbfsNotBreakingDeps,
+	CategoryOrphansBreakingDeps,
+	CategoryOrphansBreakingDepsStale,
+	CategoryOrphansNotBreakingDeps,
+	CategoryOrphansNotBreakingDepsStale,
+}
+
+func (c Category) String() string {
+	return string(c)
+}
+
+func (c Category) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+func (c *Category) UnmarshalText(text []byte) error {
+	s := Category(text)
+	if !slices.Contains(Categories, s) {
+		return fmt.Errorf("invalid category: %q", s)
+	}
+	*c = s
+	return nil
+}
+
+// categoryFilter returns a function that reports whether a package is
+// selected by options.Categories and options.ExcludeCategories.
+func (o *Orphans) categoryFilter(
+	options OrphanedFilterOptions,
+) (func(string) bool, error) {
+	collect := func(categories []Category) (mapset.Set[string], error) {
+		s := mapset.NewThreadUnsafeSet[string]()
+		for _, c := range categories {
+			pkgs := o.CategoryPackages(c)
+			if pkgs == nil {
+				return s, fmt.Errorf("invalid category: %q", c)
+			}
+			s.Append(*pkgs...)
+		}
+		return s, nil
+	}
+	include, err := collect(options.Categories)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := collect(options.ExcludeCategories)
+	if err != nil {
+		return nil, err
+	}
+	return func(pkg string) bool {
+		if len(options.Categories) > 0 && !include.Contains(pkg) {
+			return false
+		}
+		return !exclude.Contains(pkg)
+	}, nil
+}
+
+// CategoryPackages returns a pointer to the package list for c so callers can
+// read or replace it.
+// It returns nil for an unknown category.
+func (o *Orphans) CategoryPackages(c Category) *[]string {
+	switch c {
+	case CategoryOrphansBreakingDeps:
+		return &o.OrphansBreakingDeps
+	case CategoryOrphansBreakingDepsStale:
+		return &o.OrphansBreakingDepsStale
+	case CategoryOrphansNotBreakingDeps:
+		return &o.OrphansNotBreakingDeps
+	case CategoryOrphansNotBreakingDepsStale:
+		return &o.OrphansNotBreakingDepsStale
+	case CategoryFtbfsBreakingDeps:
+		return &o.FtbfsBreakingDeps
+	case CategoryFtbfsNotBreakingDeps:
+		return &o.FtbfsNotBreakingDeps
+	}
+	return nil
+}
+
+// PackageCategories returns a map of package name -> the categories that
+// package is listed in.
+func (o *Orphans) PackageCategories() map[string][]Category {
+	r := map[string][]Category{}
+	for _, c := range Categories {
+		for _, p := range *o.CategoryPackages(c) {
+			r[p] = append(r[p], c)
+		}
+	}
+	return r
+}
+
+// DirectMaintainers returns a map of package name -> the users and @groups
+// that directly maintain it according to [Orphans.AffectedPeople].
+func (o *Orphans) DirectMaintainers() map[string][]string {
+	r := map[string][]string{}
+	for person, pkgs := range o.AffectedPeople {
+		for _, pkg := range pkgs {
+			r[pkg] = append(r[pkg], person)
+		}
+	}
+	for _, people := range r {
+		slices.Sort(people)
+	}
+	return r
+}
+
+// OrphanRow summarizes an orphaned package for machine-readable output.
+type OrphanRow struct {
+	Package      string     `json:"package"`
+	StatusChange *time.Time `json:"status_change"`
+	// nil if the package has no status_change
+	RetireAt        *time.Time `json:"retire_at"`
+	GolangExemption bool       `json:"golang_exemption"`
+	Exemptions      []string   `json:"exemptions"`
+	Categories      []Category `json:"categories"`
+	Maintainers     []string   `json:"maintainers"`
+}
+
+// Rows returns an [OrphanRow] for each package in pkgs.
+// The retirement date is computed with the same options as [Orphans.Deadlines],
+// so options.Duration should be the grace period.
+func (o *Orphans) Rows(
+	pkgs []string,
+	options OrphanedFilterOptions,
+) ([]OrphanRow, error) {
+	deadlines, err := o.Deadlines(options)
+	if err != nil {
+		return nil, err
+	}
+	dm := make(map[string]Deadline, len(deadlines))
+	for _, d := range deadlines {
+		dm[d.Package] = d
+	}
+	rules, err := o.exemptionRules(options)
+	if err != nil {
+		return nil, err
+	}
+	categories := o.PackageCategories()
+	maintainers := o.DirectMaintainers()
+	rows := make([]OrphanRow, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		exemptions := rules.names(pkg)
+		row := OrphanRow{
+			Package:         pkg,
+			GolangExemption: slices.Contains(exemptions, GolangExemptionPolicyName),
+			Exemptions:      exemptions,
+			Categories:      categories[pkg],
+			Maintainers:     maintainers[pkg],
+		}
+		if d, ok := dm[pkg]; ok {
+			row.StatusChange = d.StatusChange
+			if d.StatusChange != nil {
+				row.RetireAt = &d.RetireAt
+			}
+		} else if t, ok := o.StatusChange[pkg]; ok {
+			// The package is excluded from the deadlines, so compute
+			// the date without the exemption rules.
+			retireAt := t.Add(options.Duration)
+			row.StatusChange = &t
+			row.RetireAt = &retireAt
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
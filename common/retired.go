@@ -0,0 +1,141 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// RetiredFile is the list of packages retired by the last retire.py run.
+// It's published next to orphans.json.
+const RetiredFile = "retired"
+
+// Retired is the list of packages from the last retirement run.
+type Retired struct {
+	Packages []string `json:"packages"`
+	// When the list was last modified, i.e., the end of the retirement run
+	ModTime time.Time `json:"mod_time"`
+}
+
+// ParsePackageList parses a list of packages with one package per line, such
+// as the list of retired packages written by retire.py's --lf option.
+// Blank lines and lines starting with "#" are ignored.
+func ParsePackageList(r io.Reader) ([]string, error) {
+	var pkgs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pkgs = append(pkgs, strings.Fields(line)[0])
+	}
+	return pkgs, scanner.Err()
+}
+
+// LoadPackageList loads a list of packages in the [ParsePackageList] format
+// from path.
+func LoadPackageList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	pkgs, err := ParsePackageList(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return pkgs, nil
+}
+
+// LoadRetired loads the retired file at path.
+func LoadRetired(path string) (*Retired, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", RetiredFile, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := ParsePackageList(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &Retired{Packages: pkgs, ModTime: info.ModTime().UTC()}, nil
+}
+
+// RetireStatus summarizes what still needs action after a retirement run.
+type RetireStatus struct {
+	RetiredAt time.Time `json:"retired_at"`
+	// The orphans data finished before the retirement run, so retired
+	// packages are expected to still be listed.
+	DataOutdated bool `json:"data_outdated"`
+	// Packages that were retired but are still listed as orphans
+	StillOrphaned []string `json:"still_orphaned"`
+	// Packages that should have been retired by the run but weren't
+	NotRetired []string `json:"not_retired"`
+	// Packages that are eligible for retirement now but weren't expected to
+	// be retired by the run
+	Pending []string `json:"pending"`
+}
+
+// NeedsAction returns whether any packages need attention.
+func (s *RetireStatus) NeedsAction() bool {
+	return len(s.StillOrphaned)+len(s.NotRetired)+len(s.Pending) > 0
+}
+
+// RetireStatus compares o to the retired list.
+// Packages are expected to be retired if they were eligible for retirement
+// at retired.ModTime according to options.
+// If toRetire is not nil, it's used as the list of packages that were
+// supposed to be retired instead.
+func (o *Orphans) RetireStatus(
+	retired *Retired,
+	toRetire []string,
+	options OrphanedFilterOptions,
+	now time.Time,
+) (*RetireStatus, error) {
+	s := &RetireStatus{
+		RetiredAt:     retired.ModTime,
+		StillOrphaned: []string{},
+		NotRetired:    []string{},
+		Pending:       []string{},
+	}
+	s.DataOutdated = o.FinishedAt != nil && o.FinishedAt.Before(retired.ModTime)
+	orphans := mapset.NewThreadUnsafeSet(o.Orphans...)
+	retiredSet := mapset.NewThreadUnsafeSet(retired.Packages...)
+	s.StillOrphaned = mapset.Sorted(retiredSet.Intersect(orphans))
+
+	deadlines, err := o.Deadlines(options)
+	if err != nil {
+		return s, err
+	}
+	expected := mapset.NewThreadUnsafeSet(toRetire...)
+	if toRetire == nil {
+		for _, d := range deadlines {
+			if d.Eligible(retired.ModTime) {
+				expected.Add(d.Package)
+			}
+		}
+	}
+	for _, d := range deadlines {
+		if d.Eligible(now) && !expected.Contains(d.Package) &&
+			!retiredSet.Contains(d.Package) {
+			s.Pending = append(s.Pending, d.Package)
+		}
+	}
+	for _, pkg := range mapset.Sorted(expected.Difference(retiredSet)) {
+		// Packages that were adopted since don't need to be retired.
+		if orphans.Contains(pkg) {
+			s.NotRetired = append(s.NotRetired, pkg)
+		}
+	}
+	return s, nil
+}
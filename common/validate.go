@@ -0,0 +1,344 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is a single issue found by validation.
+type Problem struct {
+	Severity Severity `json:"severity"`
+	// A short identifier for the type of check that failed
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Check, p.Message)
+}
+
+// ValidationReport collects the problems found in orphans data.
+type ValidationReport struct {
+	Problems []Problem `json:"problems"`
+}
+
+func (r *ValidationReport) add(severity Severity, check string, format string, a ...any) {
+	r.Problems = append(r.Problems, Problem{severity, check, fmt.Sprintf(format, a...)})
+}
+
+// Errors returns the problems with [SeverityError].
+func (r *ValidationReport) Errors() []Problem {
+	var errs []Problem
+	for _, p := range r.Problems {
+		if p.Severity == SeverityError {
+			errs = append(errs, p)
+		}
+	}
+	return errs
+}
+
+// Err returns an error that describes every problem with [SeverityError] or
+// nil if there are none.
+func (r *ValidationReport) Err() error {
+	var err error
+	for _, p := range r.Errors() {
+		err = errors.Join(err, errors.New(p.String()))
+	}
+	if err != nil {
+		return fmt.Errorf("orphans data failed validation:\n%w", err)
+	}
+	return nil
+}
+
+// orphansFields returns the JSON keys of [Orphans] and whether each one is
+// required.
+func orphansFields() map[string]bool {
+	r := map[string]bool{}
+	t := reflect.TypeFor[Orphans]()
+	for i := range t.NumField() {
+		tag := t.Field(i).Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		r[name] = !slices.Contains(strings.Split(opts, ","), "omitempty")
+	}
+	return r
+}
+
+// CheckSchema compares the keys in the orphans.json data to the fields that
+// [Orphans] knows about and then decodes the data.
+func CheckSchema(data []byte, report *ValidationReport) (*Orphans, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode orphans.json: %w", err)
+	}
+	fields := orphansFields()
+	for _, key := range slices.Sorted(maps.Keys(raw)) {
+		if _, ok := fields[key]; !ok && !isExemptionField(key) {
+			report.add(SeverityWarning, "schema", "unknown field %q", key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		if _, ok := raw[key]; !ok && fields[key] {
+			report.add(SeverityError, "schema", "missing field %q", key)
+		}
+	}
+	// Unknown fields were already reported above.
+	// Decoding continues after type errors, so report them instead of
+	// failing.
+	var o Orphans
+	err := json.Unmarshal(data, &o)
+	var uerr *json.UnmarshalTypeError
+	if errors.As(err, &uerr) {
+		report.add(SeverityError, "schema", "%v", uerr)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to decode orphans.json: %w", err)
+	}
+	return &o, nil
+}
+
+// Validate checks that o is internally consistent and adds any problems to
+// report.
+func (o *Orphans) Validate(report *ValidationReport) {
+	orphans := mapset.NewThreadUnsafeSet[string]()
+	for _, pkg := range o.Orphans {
+		if !orphans.Add(pkg) {
+			report.add(SeverityWarning, "orphans", "%s is listed more than once", pkg)
+		}
+		if _, ok := o.StatusChange[pkg]; !ok {
+			report.add(
+				SeverityError, "status_change",
+				"orphan %s has no status_change entry", pkg,
+			)
+		}
+	}
+	if o.FinishedAt == nil {
+		report.add(SeverityWarning, "timestamps", "finished_at is missing")
+	} else {
+		if o.StartedAt != nil && o.FinishedAt.Before(*o.StartedAt) {
+			report.add(SeverityError, "timestamps", "finished_at is before started_at")
+		}
+		for _, pkg := range o.Orphans {
+			if t, ok := o.StatusChange[pkg]; ok && t.After(*o.FinishedAt) {
+				report.add(
+					SeverityWarning, "status_change",
+					"status_change for %s (%s) is after finished_at",
+					pkg, t.Format(time.RFC3339),
+				)
+			}
+		}
+	}
+
+	for _, person := range slices.Sorted(maps.Keys(o.AffectedPeople)) {
+		all, ok := o.AllAffectedPeople[person]
+		if !ok {
+			report.add(
+				SeverityError, "affected_people",
+				"%s is in affected_people but not all_affected_people", person,
+			)
+			continue
+		}
+		allset := mapset.NewThreadUnsafeSet(all...)
+		for _, pkg := range o.AffectedPeople[person] {
+			if !allset.Contains(pkg) {
+				report.add(
+					SeverityError, "affected_people",
+					"%s is affected by %s in affected_people"+
+						" but not all_affected_people",
+					person, pkg,
+				)
+			}
+		}
+	}
+	for _, person := range slices.Sorted(maps.Keys(o.AllAffectedPeople)) {
+		for _, pkg := range o.AllAffectedPeople[person] {
+			if !orphans.Contains(pkg) {
+				report.add(
+					SeverityWarning, "affected_people",
+					"%s is affected by %s, which is not orphaned", person, pkg,
+				)
+			}
+		}
+	}
+
+	for _, c := range []Category{
+		CategoryOrphansBreakingDeps,
+		CategoryOrphansBreakingDepsStale,
+		CategoryOrphansNotBreakingDeps,
+		CategoryOrphansNotBreakingDepsStale,
+	} {
+		for _, pkg := range *o.CategoryPackages(c) {
+			if !orphans.Contains(pkg) {
+				report.add(
+					SeverityError, "categories",
+					"%s is listed in %s but not in orphans", pkg, c,
+				)
+			}
+		}
+	}
+	breaking, notBreaking := o.BreakingDeps(), o.NotBreakingDeps()
+	for _, pkg := range mapset.Sorted(breaking.Intersect(notBreaking)) {
+		report.add(
+			SeverityError, "categories",
+			"%s is listed as both breaking and not breaking dependencies", pkg,
+		)
+	}
+}
+
+var reportTimeRe = regexp.MustCompile(`^Report (started|finished) at (.+?)\s*$`)
+
+var reportTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999 MST",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.UnixDate,
+}
+
+// ReportTimes contains the timestamps from the orphans.txt report, if
+// present.
+type ReportTimes struct {
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+// ParseReportTimes finds the "Report started at" and "Report finished at"
+// lines in orphans.txt.
+func ParseReportTimes(r io.Reader) (*ReportTimes, error) {
+	var rt ReportTimes
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		m := reportTimeRe.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		for _, layout := range reportTimeLayouts {
+			t, err := time.Parse(layout, m[2])
+			if err != nil {
+				continue
+			}
+			t = t.UTC()
+			if m[1] == "started" {
+				rt.StartedAt = &t
+			} else {
+				rt.FinishedAt = &t
+			}
+			break
+		}
+	}
+	return &rt, scanner.Err()
+}
+
+// sameSecond compares two optional timestamps at second precision.
+// It returns true if either is missing.
+func sameSecond(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
+
+// SameRun returns whether the orphans.txt timestamps in rt match o.
+// Missing timestamps are not considered a mismatch.
+func (o *Orphans) SameRun(rt *ReportTimes) bool {
+	return sameSecond(rt.StartedAt, o.StartedAt) &&
+		sameSecond(rt.FinishedAt, o.FinishedAt)
+}
+
+// ValidateReport checks that the orphans.txt report in txt matches o and adds
+// any problems to report.
+func (o *Orphans) ValidateReport(txt []byte, report *ValidationReport) error {
+	rt, err := ParseReportTimes(bytes.NewReader(txt))
+	if err != nil {
+		return err
+	}
+	if !o.SameRun(rt) {
+		report.add(
+			SeverityError, "txt",
+			"orphans.txt and orphans.json are from different runs",
+		)
+	}
+	tree, err := ParseDepTree(bytes.NewReader(txt))
+	if err != nil {
+		return err
+	}
+	orphans := mapset.NewThreadUnsafeSet(o.Orphans...)
+	breaking := o.BreakingDeps()
+	for _, orphan := range slices.Sorted(maps.Keys(tree.Orphans)) {
+		switch {
+		case !orphans.Contains(orphan):
+			report.add(
+				SeverityError, "txt",
+				"orphans.txt lists dependents of %s, which is not in orphans.json",
+				orphan,
+			)
+		case !breaking.Contains(orphan):
+			report.add(
+				SeverityWarning, "txt",
+				"orphans.txt lists dependents of %s,"+
+					" which does not break dependencies in orphans.json",
+				orphan,
+			)
+		}
+	}
+	if len(tree.Orphans) > 0 {
+		for _, orphan := range mapset.Sorted(breaking) {
+			if _, ok := tree.Orphans[orphan]; !ok {
+				report.add(
+					SeverityWarning, "txt",
+					"%s breaks dependencies but has no dependency tree in orphans.txt",
+					orphan,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateOrphansFiles validates orphans.json at jsonPath and checks it
+// against orphans.txt at txtPath.
+// Set txtPath to an empty string to skip the orphans.txt checks.
+func ValidateOrphansFiles(jsonPath, txtPath string) (*ValidationReport, error) {
+	report := &ValidationReport{}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return report, fmt.Errorf("failed to load orphans.json: %w", err)
+	}
+	o, err := CheckSchema(data, report)
+	if err != nil {
+		return report, err
+	}
+	o.Validate(report)
+	if txtPath != "" {
+		txt, err := os.ReadFile(txtPath)
+		if err != nil {
+			return report, fmt.Errorf("failed to load orphans.txt: %w", err)
+		}
+		if err = o.ValidateReport(txt, report); err != nil {
+			return report, fmt.Errorf("failed to parse orphans.txt: %w", err)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"go.gtmx.me/goorphans/common"
+)
+
+// ExemptionConfig is an exemption policy from the [[orphans.exemptions]]
+// config tables.
+// The exempt packages are combined from Packages, File, and Field.
+type ExemptionConfig struct {
+	Name     string   `toml:"name"`
+	Packages []string `toml:"packages"`
+	// File with one package per line
+	File string `toml:"file"`
+	// Field in orphans.json, e.g., "rust_exemptions"
+	Field    string    `toml:"field"`
+	FlagDate time.Time `toml:"flag-date"`
+	// 0 uses the default grace period
+	GraceWeeks int `toml:"grace-weeks"`
+	// Defaults to flagdate
+	Mode *common.ExemptionMode `toml:"mode"`
+}
+
+// Policy resolves the exempt packages for the orphans data in o.
+func (c *ExemptionConfig) Policy(o *common.Orphans) (common.ExemptionPolicy, error) {
+	p := common.ExemptionPolicy{
+		Name:     c.Name,
+		Packages: append([]string{}, c.Packages...),
+		Mode:     common.ExemptionModeFlagDate,
+		FlagDate: c.FlagDate,
+		Grace:    common.Weeks(c.GraceWeeks),
+	}
+	if c.Mode != nil {
+		p.Mode = *c.Mode
+	}
+	if c.File != "" {
+		pkgs, err := common.LoadPackageList(c.File)
+		if err != nil {
+			return p, fmt.Errorf("exemption policy %q: %w", c.Name, err)
+		}
+		p.Packages = append(p.Packages, pkgs...)
+	}
+	if c.Field != "" {
+		pkgs, _ := o.ExemptionField(c.Field)
+		p.Packages = append(p.Packages, pkgs...)
+	}
+	return p, nil
+}
+
+// ExemptionPolicies resolves the configured exemption policies for the
+// orphans data in o.
+func (c *OrphansConfig) ExemptionPolicies(
+	o *common.Orphans,
+) ([]common.ExemptionPolicy, error) {
+	policies := make([]common.ExemptionPolicy, 0, len(c.Exemptions))
+	seen := map[string]bool{}
+	for _, e := range c.Exemptions {
+		if e.Name == "" {
+			return nil, fmt.Errorf("orphans.exemptions: every policy needs a name")
+		}
+		if seen[e.Name] {
+			return nil, fmt.Errorf("orphans.exemptions: duplicate policy %q", e.Name)
+		}
+		seen[e.Name] = true
+		p, err := e.Policy(o)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
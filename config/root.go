@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"reflect"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/pelletier/go-toml/v2"
@@ -20,6 +21,22 @@ var OrphansTo = []string{
 
 var OrphansReplyTo = "devel@lists.fedoraproject.org"
 
+// OrphansSubject is the default for OrphansConfig.Subject
+var OrphansSubject = "Orphaned packages looking for new maintainers"
+
+var EPELOrphansTo = []string{
+	"epel-devel@lists.fedoraproject.org",
+}
+
+var EPELOrphansReplyTo = "epel-devel@lists.fedoraproject.org"
+
+// DefaultOrphansMaxAge is the default for OrphansConfig.MaxAge in hours
+const DefaultOrphansMaxAge = 24.0
+
+// DefaultOrphansThreadWeeks is the default for OrphansConfig.ThreadWeeks.
+// It's roughly the length of a Fedora release cycle.
+const DefaultOrphansThreadWeeks = 26
+
 type Config struct {
 	SMTP    SMTPConfig    `toml:"smtp"    envPrefix:"SMTP_"`
 	FASJSON FASJSONConfig `toml:"fasjson" envPrefix:"FASJSON_"`
@@ -34,12 +51,34 @@ type FASJSONConfig struct {
 }
 
 type OrphansConfig struct {
-	BaseURL          string   `toml:"baseurl"            env:"BASEURL"`
-	Download         bool     `toml:"download"           env:"DOWNLOAD"`
-	To               []string `toml:"to"                 env:"TO"`
-	ReplyTo          string   `toml:"reply-to"           env:"REPLY_TO"`
-	BCC              []string `toml:"bcc"                env:"BCC"`
-	DirectMaintsOnly bool     `toml:"direct-maints-only" env:"DIRECT_MAINTS_ONLY"`
+	BaseURL  string   `toml:"baseurl"            env:"BASEURL"`
+	Download bool     `toml:"download"           env:"DOWNLOAD"`
+	To       []string `toml:"to"                 env:"TO"`
+	ReplyTo  string   `toml:"reply-to"           env:"REPLY_TO"`
+	BCC      []string `toml:"bcc"                env:"BCC"`
+	// text/template for the announcement subject
+	Subject          string `toml:"subject"            env:"SUBJECT"`
+	DirectMaintsOnly bool   `toml:"direct-maints-only" env:"DIRECT_MAINTS_ONLY"`
+	// Path to the database of downloaded orphans data. Empty disables history.
+	HistoryDB string `toml:"history-db"         env:"HISTORY_DB"`
+	// Maximum age of the orphans data in hours before announce and list
+	// refuse to run. 0 disables the check.
+	MaxAge float64 `toml:"max-age"            env:"MAX_AGE"`
+	// Send each announcement as a reply to the previous one
+	Thread bool `toml:"thread"             env:"THREAD"`
+	// Start a new thread once the current one is older than this many weeks.
+	// 0 keeps the same thread until a new one is started manually.
+	ThreadWeeks int `toml:"thread-weeks"       env:"THREAD_WEEKS"`
+	// Exemption policies in addition to the builtin Golang exemption
+	Exemptions []ExemptionConfig `toml:"exemptions"`
+	// Announcement settings for packages that are only orphaned in EPEL
+	EPEL EPELOrphansConfig `toml:"epel"                                        envPrefix:"EPEL_"`
+}
+
+type EPELOrphansConfig struct {
+	To      []string `toml:"to"       env:"TO"`
+	ReplyTo string   `toml:"reply-to" env:"REPLY_TO"`
+	BCC     []string `toml:"bcc"      env:"BCC"`
 }
 
 type NagsConfig struct {
@@ -54,8 +93,14 @@ func LoadConfig(p string) (*Config, error) {
 	}
 	config.FASJSON.TTL = fasjson.DefaultTTL
 	config.FASJSON.DB = path.Join(cacheDir, "fasjson.db")
+	config.SMTP.QueueDB = path.Join(cacheDir, "outbox.db")
+	config.SMTP.Retries = DefaultSMTPRetries
+	config.SMTP.RetryDelay = DefaultSMTPRetryDelay
 	// config.CacheDir = cacheDir
 	config.Orphans.BaseURL = common.OrphansBaseURL
+	config.Orphans.HistoryDB = path.Join(cacheDir, "history.db")
+	config.Orphans.MaxAge = DefaultOrphansMaxAge
+	config.Orphans.ThreadWeeks = DefaultOrphansThreadWeeks
 
 	wasDefault := false
 	if p == DefaultSentinel {
@@ -78,7 +123,14 @@ func LoadConfig(p string) (*Config, error) {
 			return nil, fmt.Errorf("failed to parse %s: %w", p, err)
 		}
 	}
-	err = env.ParseWithOptions(&config, env.Options{Prefix: "GOORPHANS_"})
+	err = env.ParseWithOptions(&config, env.Options{
+		Prefix: "GOORPHANS_",
+		// Command values (e.g., smtp.sendmail-cmd) are strings when set
+		// through the environment.
+		FuncMap: map[reflect.Type]env.ParserFunc{
+			reflect.TypeFor[any](): func(v string) (any, error) { return v, nil },
+		},
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -88,5 +140,14 @@ func LoadConfig(p string) (*Config, error) {
 	if config.Orphans.ReplyTo == "" {
 		config.Orphans.ReplyTo = OrphansReplyTo
 	}
+	if config.Orphans.Subject == "" {
+		config.Orphans.Subject = OrphansSubject
+	}
+	if config.Orphans.EPEL.To == nil {
+		config.Orphans.EPEL.To = EPELOrphansTo
+	}
+	if config.Orphans.EPEL.ReplyTo == "" {
+		config.Orphans.EPEL.ReplyTo = EPELOrphansReplyTo
+	}
 	return &config, nil
 }
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/anmitsu/go-shlex"
@@ -16,20 +18,68 @@ const (
 	portStartTLS = 587
 	portFallback = 25
 	portTLS      = 465
+
+	DefaultSMTPRetries    = 3
+	DefaultSMTPRetryDelay = 30.0
+)
+
+// Values for smtp.transport
+const (
+	TransportSMTP = "smtp"
+	// Write one .eml file per message to smtp.outgoing-dir
+	TransportDir = "dir"
+	// Append messages to the mbox file at smtp.mbox
+	TransportMbox = "mbox"
+	// Deliver messages into the Maildir at smtp.maildir
+	TransportMaildir = "maildir"
+	// Pipe messages to smtp.sendmail-cmd
+	TransportSendmail = "sendmail"
 )
 
+var transports = []string{
+	TransportSMTP, TransportDir, TransportMbox, TransportMaildir, TransportSendmail,
+}
+
+// DefaultSendmailCmd is used when smtp.sendmail-cmd is unset.
+var DefaultSendmailCmd = []string{"/usr/sbin/sendmail", "-t", "-oi"}
+
 type SMTPConfig struct {
-	Host        string `toml:"host"         env:"HOST"`
-	Port        int    `toml:"port"         env:"PORT"`
-	Username    string `toml:"username"     env:"USERNAME"`
-	Password    string `toml:"password"     env:"PASSWORD,unset"`
-	PasswordCmd any    `toml:"password-cmd" env:"PASWORD_CMD"`
-	From        string `toml:"from"         env:"FROM"`
-	Secure      string `toml:"secure"       env:"SECURE"`
+	Host        string `toml:"host"                 env:"HOST"`
+	Port        int    `toml:"port"                 env:"PORT"`
+	Username    string `toml:"username"             env:"USERNAME"`
+	Password    string `toml:"password"             env:"PASSWORD,unset"`
+	PasswordCmd any    `toml:"password-cmd"         env:"PASWORD_CMD"`
+	From        string `toml:"from"                 env:"FROM"`
+	Secure      string `toml:"secure"               env:"SECURE"`
 	// Skip TLS verification
 	InsecureSkipVerify bool `toml:"insecure-skip-verify" env:"INSECURE_SKIP_VERIFY"`
+	// How to deliver messages. One of the Transport* constants.
+	// Defaults to TransportDir if OutgoingDir is set and TransportSMTP
+	// otherwise.
+	Transport string `toml:"transport"            env:"TRANSPORT"`
 	// Write messages to a directory instead of sending them
-	OutgoingDir string `toml:"outgoing-dir" env:"OUTGOING_DIR"`
+	OutgoingDir string `toml:"outgoing-dir"         env:"OUTGOING_DIR"`
+	// Path to the mbox file for TransportMbox
+	Mbox string `toml:"mbox"                 env:"MBOX"`
+	// Path to the Maildir for TransportMaildir
+	Maildir string `toml:"maildir"              env:"MAILDIR"`
+	// Command for TransportSendmail. A string or []string of arguments.
+	SendmailCmd any `toml:"sendmail-cmd"         env:"SENDMAIL_CMD"`
+	// Envelope sender. Defaults to the From address.
+	EnvelopeFrom string `toml:"envelope-from"        env:"ENVELOPE_FROM"`
+	// Add messages to the persistent outbox at QueueDB before sending them
+	Queue   bool   `toml:"queue"                env:"QUEUE"`
+	QueueDB string `toml:"queue-db"             env:"QUEUE_DB"`
+	// Maximum number of Bcc recipients per message.
+	// Messages with more recipients are split into batches.
+	BCCBatchSize int `toml:"bcc-batch-size"       env:"BCC_BATCH_SIZE"`
+	// Maximum number of messages to send per minute
+	RateLimit int `toml:"rate-limit"           env:"RATE_LIMIT"`
+	// Number of times to retry messages after temporary failures
+	Retries int `toml:"retries"              env:"RETRIES"`
+	// Delay in seconds before the first retry.
+	// The delay doubles after each attempt.
+	RetryDelay float64 `toml:"retry-delay"          env:"RETRY_DELAY"`
 }
 
 // Validate is overcomplicated code to parse SMTPConfig and handle unset
@@ -43,10 +93,59 @@ func (s *SMTPConfig) Validate() error {
 		adderr(fmt.Errorf(format, a...))
 	}
 
-	if s.OutgoingDir != "" {
+	if s.BCCBatchSize < 0 {
+		adderrf("smtp.bcc-batch-size must not be negative")
+	}
+	if s.RateLimit < 0 {
+		adderrf("smtp.rate-limit must not be negative")
+	}
+	if s.Retries < 0 {
+		adderrf("smtp.retries must not be negative")
+	}
+	if s.RetryDelay < 0 {
+		adderrf("smtp.retry-delay must not be negative")
+	}
+	if s.Transport == "" {
+		s.Transport = TransportSMTP
+		if s.OutgoingDir != "" {
+			s.Transport = TransportDir
+		}
+	}
+	if s.Transport != TransportSMTP {
 		s.Host = ""
+	}
+	switch s.Transport {
+	case TransportDir:
+		if s.OutgoingDir == "" {
+			adderrf("smtp.outgoing-dir is required for the %q transport", s.Transport)
+			break
+		}
 		adderr(os.MkdirAll(s.OutgoingDir, 0o755))
-	} else {
+	case TransportMbox:
+		if s.Mbox == "" {
+			adderrf("smtp.mbox is required for the %q transport", s.Transport)
+			break
+		}
+		adderr(os.MkdirAll(filepath.Dir(s.Mbox), 0o755))
+	case TransportMaildir:
+		if s.Maildir == "" {
+			adderrf("smtp.maildir is required for the %q transport", s.Transport)
+			break
+		}
+		for _, sub := range []string{"cur", "new", "tmp"} {
+			adderr(os.MkdirAll(filepath.Join(s.Maildir, sub), 0o755))
+		}
+	case TransportSendmail:
+		args, err := s.SendmailArgs()
+		adderr(err)
+		// With -t, the To and Cc recipients would receive every batch
+		if s.BCCBatchSize > 0 && len(args) > 1 && slices.Contains(args[1:], "-t") {
+			adderrf("smtp.bcc-batch-size can't be used with sendmail -t")
+		}
+		if s.From == "" {
+			adderrf("missing required configuration keys: smtp.from")
+		}
+	case TransportSMTP:
 		if s.Password == "" && s.PasswordCmd != nil {
 			cmd, err := parseCmd(s.PasswordCmd, "smtp.password_cmd")
 			if err == nil {
@@ -106,12 +205,29 @@ func (s *SMTPConfig) Validate() error {
 				strings.Join(missing, "; "),
 			)
 		}
-
+	default:
+		adderrf(
+			"invalid smtp.transport value %q: must be one of %s",
+			s.Transport, strings.Join(transports, ", "),
+		)
 	}
 
 	return allerr
 }
 
+// SendmailArgs returns the parsed smtp.sendmail-cmd or [DefaultSendmailCmd]
+// if it's unset.
+func (s *SMTPConfig) SendmailArgs() ([]string, error) {
+	if s.SendmailCmd == nil {
+		return slices.Clone(DefaultSendmailCmd), nil
+	}
+	args, err := parseCmd(s.SendmailCmd, "smtp.sendmail-cmd")
+	if err == nil && len(args) == 0 {
+		err = errors.New("smtp.sendmail-cmd is empty")
+	}
+	return args, err
+}
+
 func parseCmd(cmd any, key string) ([]string, error) {
 	switch cmd := cmd.(type) {
 	case string:
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"net/http"
 	"slices"
 	"strings"
 	"time"
@@ -20,6 +21,9 @@ var schema string
 
 var DefaultTTL = (time.Hour * 24).Seconds()
 
+// ErrNoEmail is returned when a user has no email addresses.
+var ErrNoEmail = errors.New("user has no email addresses")
+
 // EmailCacheClient is a wrapper around FASJSON that caches username and group
 // -> email mappings.
 // Each function caches its result in the SQLite database.
@@ -93,6 +97,9 @@ func (cache *EmailCacheClient) GetUserEmail(username string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if len(user.Emails) == 0 {
+		return "", fmt.Errorf("%s: %w", username, ErrNoEmail)
+	}
 	result = user.Emails[0]
 	err = cache.insertUserEmail(username, user.Emails[0])
 	return result, err
@@ -257,3 +264,69 @@ func (cache *EmailCacheClient) GetAllEmailsMap(
 ) (map[string]string, error) {
 	return cache.GetIterEmailsMap(slices.Values(names))
 }
+
+// Resolved is the result of [EmailCacheClient.ResolveEmails].
+type Resolved struct {
+	// username -> email for users that were named directly
+	Direct map[string]string
+	// username -> email for users that were only included as members of a
+	// @group
+	Members map[string]string
+	// Users and @groups that don't exist or have no email address
+	Unresolved map[string]error
+}
+
+// IsNotFound reports whether err means that a user or group couldn't be
+// resolved, as opposed to a network or database failure.
+func IsNotFound(err error) bool {
+	var serr *common.StatusCodeError
+	return errors.Is(err, ErrNoEmail) ||
+		(errors.As(err, &serr) && serr.StatusCode == http.StatusNotFound)
+}
+
+// ResolveEmails is like [EmailCacheClient.GetIterEmailsMap], but it records
+// users and groups that can't be resolved instead of failing and keeps track
+// of which users were included through a group.
+func (cache *EmailCacheClient) ResolveEmails(names iter.Seq[string]) (*Resolved, error) {
+	r := &Resolved{
+		Direct:     map[string]string{},
+		Members:    map[string]string{},
+		Unresolved: map[string]error{},
+	}
+	direct := mapset.NewThreadUnsafeSet[string]()
+	members := mapset.NewThreadUnsafeSet[string]()
+	for name := range names {
+		group, found := strings.CutPrefix(name, "@")
+		if !found {
+			direct.Add(name)
+			continue
+		}
+		m, err := cache.GetMembers(group)
+		if IsNotFound(err) {
+			r.Unresolved[name] = err
+			continue
+		} else if err != nil {
+			return r, err
+		}
+		members.Append(m...)
+	}
+	direct.Remove(common.OrphanUID)
+	members = members.Difference(direct)
+	members.Remove(common.OrphanUID)
+	for _, set := range []struct {
+		users mapset.Set[string]
+		dest  map[string]string
+	}{{direct, r.Direct}, {members, r.Members}} {
+		for user := range mapset.Elements(set.users) {
+			email, err := cache.GetUserEmail(user)
+			if IsNotFound(err) {
+				r.Unresolved[user] = err
+				continue
+			} else if err != nil {
+				return r, err
+			}
+			set.dest[user] = email
+		}
+	}
+	return r, nil
+}
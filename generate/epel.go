@@ -0,0 +1,65 @@
+package generate
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/distgit"
+)
+
+// EPELOrphans are packages that are orphaned in EPEL but still have a Fedora
+// point of contact.
+type EPELOrphans struct {
+	Orphans []string `json:"orphans"`
+	// Package -> Fedora point of contact
+	FedoraPOC map[string]string `json:"fedora_poc"`
+	// Package -> users and @groups that maintain it
+	Maintainers map[string][]string `json:"maintainers"`
+	// User or @group -> EPEL orphans that they maintain
+	AffectedPeople map[string][]string `json:"affected_people"`
+	GeneratedAt    time.Time           `json:"generated_at"`
+}
+
+func isEPELOrphan(poc distgit.ExtrasPagurePOCTypes) bool {
+	return poc.EPEL == common.OrphanUID && poc.Fedora != "" && !isOrphan(poc)
+}
+
+// GenerateEPEL finds the packages whose EPEL point of contact is orphan while
+// the Fedora point of contact is a real person.
+// maintainers is the result of [Maintainers].
+func GenerateEPEL(
+	poc *distgit.ExtrasPagurePOC,
+	maintainers map[string][]string,
+	now time.Time,
+) *EPELOrphans {
+	e := &EPELOrphans{
+		Orphans:        []string{},
+		FedoraPOC:      map[string]string{},
+		Maintainers:    map[string][]string{},
+		AffectedPeople: map[string][]string{},
+		GeneratedAt:    now.UTC().Truncate(time.Second),
+	}
+	for _, pkg := range slices.Sorted(maps.Keys(poc.RPMS)) {
+		p := poc.RPMS[pkg]
+		if !isEPELOrphan(p) {
+			continue
+		}
+		e.Orphans = append(e.Orphans, pkg)
+		e.FedoraPOC[pkg] = p.Fedora
+		people := slices.DeleteFunc(slices.Clone(maintainers[pkg]), func(s string) bool {
+			return s == common.OrphanUID
+		})
+		e.Maintainers[pkg] = people
+		for _, person := range people {
+			e.AffectedPeople[person] = append(e.AffectedPeople[person], pkg)
+		}
+	}
+	return e
+}
+
+// People returns the sorted keys of AffectedPeople.
+func (e *EPELOrphans) People() []string {
+	return slices.Sorted(maps.Keys(e.AffectedPeople))
+}
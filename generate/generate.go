@@ -0,0 +1,328 @@
+// Package generate computes the orphans report from local repository
+// metadata and the distgit extras data, replacing find_unblocked_orphans.py.
+package generate
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/distgit"
+)
+
+type Options struct {
+	// Used to determine which packages are orphaned
+	POC *distgit.ExtrasPagurePOC
+	// Source package name -> users and @groups that maintain it
+	Maintainers map[string][]string
+	// Binary and source packages from [LoadRepos]
+	Packages []*Package
+	// Packages that fail to build from source.
+	// They are sorted into ftbfs_breaking_deps and ftbfs_not_breaking_deps.
+	FTBFS []string
+	// The orphans data from the last run. Required.
+	// The status_change of packages that are still orphaned is copied from
+	// Previous.
+	// Packages that were not orphaned in Previous get the time that the report
+	// was started, so Previous should be recent.
+	Previous *common.Orphans
+	// Defaults to [time.Now]
+	Now func() time.Time
+}
+
+// Report is the generated orphans data.
+type Report struct {
+	Orphans *common.Orphans
+	DepTree *common.DepTree
+}
+
+// index maps capabilities to the packages that provide and require them.
+type index struct {
+	// Source package name -> its binary packages
+	binaries map[string][]*Package
+	// Source package name -> the source package itself
+	sources map[string]*Package
+	// Capability -> packages that provide it
+	providers map[string][]*Package
+	// Capability -> packages that require it
+	requirers map[string][]*Package
+}
+
+func newIndex(pkgs []*Package) *index {
+	idx := &index{
+		binaries:  map[string][]*Package{},
+		sources:   map[string]*Package{},
+		providers: map[string][]*Package{},
+		requirers: map[string][]*Package{},
+	}
+	for _, p := range pkgs {
+		if p.IsSource() {
+			idx.sources[p.Name] = p
+		} else {
+			src := p.SourceName()
+			idx.binaries[src] = append(idx.binaries[src], p)
+			for _, c := range p.capabilities() {
+				idx.providers[c] = append(idx.providers[c], p)
+			}
+		}
+		for _, req := range p.Requires {
+			if !req.Rich() {
+				idx.requirers[req.Name] = append(idx.requirers[req.Name], p)
+			}
+		}
+	}
+	return idx
+}
+
+// capabilities returns the names of everything p provides, including its
+// files.
+func (p *Package) capabilities() []string {
+	caps := make([]string, 0, len(p.Provides)+len(p.Files)+1)
+	caps = append(caps, p.Name)
+	for _, prov := range p.Provides {
+		caps = append(caps, prov.Name)
+	}
+	return append(caps, p.Files...)
+}
+
+// sourceNames returns all source package names in the repositories.
+func (idx *index) sourceNames() mapset.Set[string] {
+	s := mapset.NewThreadUnsafeSet[string]()
+	for name := range idx.sources {
+		s.Add(name)
+	}
+	for name := range idx.binaries {
+		s.Add(name)
+	}
+	return s
+}
+
+// brokenBy returns whether every provider of capability is built from a
+// source package in broken.
+func (idx *index) brokenBy(capability string, broken map[string]*common.Dependent) bool {
+	providers := idx.providers[capability]
+	for _, p := range providers {
+		if _, ok := broken[p.SourceName()]; !ok {
+			return false
+		}
+	}
+	return len(providers) > 0
+}
+
+// dependents finds the packages that can no longer be installed or built
+// when orphan is retired, and the packages that they can no longer be
+// installed or built without, recursively.
+//
+// Versioned requirements are matched by name only, and rich dependencies
+// are ignored.
+func (idx *index) dependents(orphan string) []*common.Dependent {
+	root := &common.Dependent{Package: orphan, Path: []string{orphan}}
+	broken := map[string]*common.Dependent{orphan: root}
+	var r []*common.Dependent
+	// Breadth-first search so each dependent gets the shortest path
+	queue := []*common.Dependent{root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, b := range idx.binaries[cur.Package] {
+			for _, c := range b.capabilities() {
+				reqs := idx.requirers[c]
+				if len(reqs) == 0 || !idx.brokenBy(c, broken) {
+					continue
+				}
+				for _, req := range reqs {
+					name := req.SourceName()
+					if _, ok := broken[name]; ok {
+						continue
+					}
+					d := &common.Dependent{
+						Package: name,
+						Depth:   cur.Depth + 1,
+						Path:    append(slices.Clone(cur.Path), name),
+					}
+					broken[name] = d
+					r = append(r, d)
+					queue = append(queue, d)
+				}
+			}
+		}
+	}
+	// List every requirement that's broken now that the full set of broken
+	// packages is known.
+	for _, d := range r {
+		pkgs := idx.binaries[d.Package]
+		if src, ok := idx.sources[d.Package]; ok {
+			pkgs = append([]*Package{src}, pkgs...)
+		}
+		for _, p := range pkgs {
+			for _, req := range p.Requires {
+				if req.Rich() || !idx.brokenBy(req.Name, broken) {
+					continue
+				}
+				d.Requires = append(d.Requires, common.Requirement{
+					NEVRA:    p.NEVRA(),
+					Requires: req.String(),
+				})
+			}
+		}
+		slices.SortFunc(d.Requires, func(a, b common.Requirement) int {
+			if c := strings.Compare(a.NEVRA, b.NEVRA); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Requires, b.Requires)
+		})
+	}
+	return r
+}
+
+// Maintainers combines the users with admin or commit access from owners
+// with the @groups from bz.
+func Maintainers(
+	owners *distgit.ExtrasPagureOwnerAlias, bz *distgit.ExtrasPagureBZ,
+) map[string][]string {
+	r := make(map[string][]string, len(owners.RPMS))
+	for pkg, users := range owners.RPMS {
+		r[pkg] = slices.Clone(users)
+	}
+	for pkg, people := range bz.RPMS {
+		for _, p := range people {
+			if strings.HasPrefix(p, "@") {
+				r[pkg] = append(r[pkg], p)
+			}
+		}
+	}
+	for _, people := range r {
+		slices.Sort(people)
+	}
+	return r
+}
+
+func isOrphan(poc distgit.ExtrasPagurePOCTypes) bool {
+	return poc.Fedora == common.OrphanUID
+}
+
+// Generate computes the orphans data.
+// Orphaned packages that are not in the repositories are assumed to be
+// retired and are not included.
+//
+// Addresses is left empty: the addresses of all_affected_people are looked up
+// in FASJSON when the report is announced instead.
+func Generate(options Options) (*Report, error) {
+	if options.Previous == nil {
+		return nil, errors.New(
+			"previous orphans data is required to determine when packages were orphaned",
+		)
+	}
+	now := options.Now
+	if now == nil {
+		now = time.Now
+	}
+	startedAt := now().UTC().Truncate(time.Second)
+	idx := newIndex(options.Packages)
+	sources := idx.sourceNames()
+
+	o := &common.Orphans{
+		Addresses:                   []string{},
+		AffectedPackages:            map[string][]string{},
+		AffectedPeople:              map[string][]string{},
+		AllAffectedPeople:           map[string][]string{},
+		FtbfsBreakingDeps:           []string{},
+		FtbfsNotBreakingDeps:        []string{},
+		Orphans:                     []string{},
+		OrphansBreakingDeps:         []string{},
+		OrphansBreakingDepsStale:    []string{},
+		OrphansNotBreakingDeps:      []string{},
+		OrphansNotBreakingDepsStale: []string{},
+		StatusChange:                map[string]time.Time{},
+		StartedAt:                   &startedAt,
+	}
+	report := &Report{
+		Orphans: o,
+		DepTree: &common.DepTree{Orphans: map[string]*common.OrphanDeps{}},
+	}
+	for _, name := range slices.Sorted(maps.Keys(options.POC.RPMS)) {
+		if isOrphan(options.POC.RPMS[name]) && sources.Contains(name) {
+			o.Orphans = append(o.Orphans, name)
+		}
+	}
+
+	allAffected := map[string]mapset.Set[string]{}
+	addAffected := func(m map[string]mapset.Set[string], pkg string, orphan string) {
+		for _, person := range options.Maintainers[pkg] {
+			if person == common.OrphanUID {
+				continue
+			}
+			if m[person] == nil {
+				m[person] = mapset.NewThreadUnsafeSet[string]()
+			}
+			m[person].Add(orphan)
+		}
+	}
+	direct := map[string]mapset.Set[string]{}
+	stale := common.Weeks(common.RetirementWeeks)
+	for _, orphan := range o.Orphans {
+		statusChange := startedAt
+		if t, ok := options.Previous.StatusChange[orphan]; ok &&
+			slices.Contains(options.Previous.Orphans, orphan) {
+			statusChange = t
+		}
+		o.StatusChange[orphan] = statusChange
+		isStale := startedAt.Sub(statusChange) >= stale
+		addAffected(direct, orphan, orphan)
+		addAffected(allAffected, orphan, orphan)
+
+		deps := idx.dependents(orphan)
+		if len(deps) == 0 {
+			if isStale {
+				o.OrphansNotBreakingDepsStale = append(
+					o.OrphansNotBreakingDepsStale,
+					orphan,
+				)
+			} else {
+				o.OrphansNotBreakingDeps = append(o.OrphansNotBreakingDeps, orphan)
+			}
+			continue
+		}
+		if isStale {
+			o.OrphansBreakingDepsStale = append(o.OrphansBreakingDepsStale, orphan)
+		} else {
+			o.OrphansBreakingDeps = append(o.OrphansBreakingDeps, orphan)
+		}
+		affected := mapset.NewThreadUnsafeSet[string]()
+		for _, d := range deps {
+			d.Maintainers = options.Maintainers[d.Package]
+			affected.Add(d.Package)
+			addAffected(allAffected, d.Package, orphan)
+		}
+		o.AffectedPackages[orphan] = mapset.Sorted(affected)
+		report.DepTree.Orphans[orphan] = &common.OrphanDeps{
+			Orphan:       orphan,
+			Count:        len(deps),
+			StatusChange: statusChange.Format(time.DateOnly),
+			Dependents:   deps,
+		}
+	}
+	for _, pkg := range mapset.Sorted(mapset.NewThreadUnsafeSet(options.FTBFS...)) {
+		if !sources.Contains(pkg) {
+			continue
+		}
+		if len(idx.dependents(pkg)) > 0 {
+			o.FtbfsBreakingDeps = append(o.FtbfsBreakingDeps, pkg)
+		} else {
+			o.FtbfsNotBreakingDeps = append(o.FtbfsNotBreakingDeps, pkg)
+		}
+	}
+	for person, pkgs := range direct {
+		o.AffectedPeople[person] = mapset.Sorted(pkgs)
+	}
+	for person, pkgs := range allAffected {
+		o.AllAffectedPeople[person] = mapset.Sorted(pkgs)
+	}
+	finishedAt := now().UTC().Truncate(time.Second)
+	o.FinishedAt = &finishedAt
+	return report, nil
+}
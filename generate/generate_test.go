@@ -0,0 +1,109 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/distgit"
+)
+
+var update = flag.Bool("update", false, "Update the expected files in testdata")
+
+func loadJSON(t *testing.T, name string, dest any) {
+	t.Helper()
+	data, err := os.ReadFile(path.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(data, dest); err != nil {
+		t.Fatalf("failed to decode %s: %v", name, err)
+	}
+}
+
+// compareGolden compares got to testdata/name or updates it with -update.
+func compareGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	p := path.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(p, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match (run go test -update to regenerate):\n%s", name, got)
+	}
+}
+
+func testOptions(t *testing.T) Options {
+	t.Helper()
+	poc := &distgit.ExtrasPagurePOC{}
+	owners := &distgit.ExtrasPagureOwnerAlias{}
+	bz := &distgit.ExtrasPagureBZ{}
+	loadJSON(t, "extras/pagure_poc.json", poc)
+	loadJSON(t, "extras/pagure_owner_alias.json", owners)
+	loadJSON(t, "extras/pagure_bz.json", bz)
+	previous, err := common.LoadOrphans(path.Join("testdata", "previous.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ftbfs, err := common.LoadPackageList(path.Join("testdata", "ftbfs.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err := LoadRepos([]string{
+		path.Join("testdata", "repos", "bin"),
+		path.Join("testdata", "repos", "src"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	return Options{
+		POC:         poc,
+		Maintainers: Maintainers(owners, bz),
+		Packages:    pkgs,
+		FTBFS:       ftbfs,
+		Previous:    previous,
+		Now:         func() time.Time { return now },
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	report, err := Generate(testOptions(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(report.Orphans); err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, common.OrphansJSON, buf.Bytes())
+
+	buf.Reset()
+	if err = report.WriteTXT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, common.OrphansTXT, buf.Bytes())
+}
+
+func TestGenerateNoPrevious(t *testing.T) {
+	options := testOptions(t)
+	options.Previous = nil
+	if _, err := Generate(options); err == nil {
+		t.Fatal("expected an error without previous orphans data")
+	}
+}
@@ -0,0 +1,325 @@
+package generate
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Dep is a single provides or requires entry from primary.xml.
+type Dep struct {
+	Name  string `xml:"name,attr"`
+	Flags string `xml:"flags,attr"`
+	Epoch string `xml:"epoch,attr"`
+	Ver   string `xml:"ver,attr"`
+	Rel   string `xml:"rel,attr"`
+}
+
+var depFlags = map[string]string{
+	"EQ": "=",
+	"LT": "<",
+	"LE": "<=",
+	"GT": ">",
+	"GE": ">=",
+}
+
+// String formats d the same way as rpm, e.g., "python3-foo >= 1.2".
+func (d Dep) String() string {
+	op, ok := depFlags[d.Flags]
+	if !ok || d.Ver == "" {
+		return d.Name
+	}
+	evr := d.Ver
+	if d.Epoch != "" && d.Epoch != "0" {
+		evr = d.Epoch + ":" + evr
+	}
+	if d.Rel != "" {
+		evr += "-" + d.Rel
+	}
+	return fmt.Sprintf("%s %s %s", d.Name, op, evr)
+}
+
+// Rich returns whether d is a rich (boolean) dependency.
+func (d Dep) Rich() bool {
+	return strings.HasPrefix(d.Name, "(")
+}
+
+// Package is a binary or source package from primary.xml.
+type Package struct {
+	Name    string `xml:"name"`
+	Arch    string `xml:"arch"`
+	Version struct {
+		Epoch string `xml:"epoch,attr"`
+		Ver   string `xml:"ver,attr"`
+		Rel   string `xml:"rel,attr"`
+	} `xml:"version"`
+	Checksum  string `xml:"checksum"`
+	SourceRPM string `xml:"format>sourcerpm"`
+	Provides  []Dep  `xml:"format>provides>entry"`
+	Requires  []Dep  `xml:"format>requires>entry"`
+	// primary.xml only includes some files.
+	// The files from filelists.xml that are needed to resolve file
+	// dependencies are appended by [LoadRepos].
+	Files []string `xml:"format>file"`
+}
+
+// NEVRA returns the package's name-[epoch:]version-release.arch.
+func (p *Package) NEVRA() string {
+	evr := p.Version.Ver + "-" + p.Version.Rel
+	if p.Version.Epoch != "" && p.Version.Epoch != "0" {
+		evr = p.Version.Epoch + ":" + evr
+	}
+	return fmt.Sprintf("%s-%s.%s", p.Name, evr, p.Arch)
+}
+
+// IsSource returns whether p is a source package.
+func (p *Package) IsSource() bool {
+	return p.Arch == "src" || p.Arch == "nosrc"
+}
+
+// SourceName returns the name of the source package (i.e., the distgit
+// repository) that p was built from.
+func (p *Package) SourceName() string {
+	if p.IsSource() || p.SourceRPM == "" {
+		return p.Name
+	}
+	// foo-1.0-1.fc44.src.rpm
+	nvr, _, _ := strings.Cut(p.SourceRPM, ".src.rpm")
+	i := strings.LastIndex(nvr, "-")
+	if i <= 0 {
+		return nvr
+	}
+	j := strings.LastIndex(nvr[:i], "-")
+	if j <= 0 {
+		return nvr[:i]
+	}
+	return nvr[:j]
+}
+
+type repomd struct {
+	Data []struct {
+		Type     string `xml:"type,attr"`
+		Location struct {
+			Href string `xml:"href,attr"`
+		} `xml:"location"`
+	} `xml:"data"`
+}
+
+// repo is a repository directory and the paths to its metadata files.
+type repo struct {
+	primary   string
+	filelists string
+	packages  []*Package
+}
+
+// openRepo reads repomd.xml from dir/repodata or from dir itself if it's
+// the repodata directory.
+func openRepo(dir string) (*repo, error) {
+	root := dir
+	p := path.Join(dir, "repodata", "repomd.xml")
+	if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+		root = path.Dir(path.Clean(dir))
+		p = path.Join(dir, "repomd.xml")
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository metadata: %w", err)
+	}
+	var md repomd
+	if err = xml.Unmarshal(data, &md); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+	}
+	r := &repo{}
+	for _, d := range md.Data {
+		switch d.Type {
+		case "primary":
+			r.primary = path.Join(root, d.Location.Href)
+		case "filelists":
+			r.filelists = path.Join(root, d.Location.Href)
+		}
+	}
+	if r.primary == "" {
+		return nil, fmt.Errorf("%s does not list primary metadata", p)
+	}
+	return r, nil
+}
+
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var err error
+	for _, c := range m.closers {
+		err = errors.Join(err, c.Close())
+	}
+	return err
+}
+
+// openMetadata opens a possibly compressed metadata file.
+// The compression format is determined by the file extension.
+func openMetadata(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	var r io.Reader
+	closers := []io.Closer{f}
+	switch path.Ext(name) {
+	case ".gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		r = gz
+		closers = append(closers, gz)
+	case ".zst":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		r = zr
+		closers = append(closers, zr.IOReadCloser())
+	case ".bz2":
+		r = bzip2.NewReader(f)
+	case ".xml":
+		r = f
+	default:
+		_ = f.Close()
+		return nil, fmt.Errorf("unsupported compression format: %s", name)
+	}
+	return &multiCloser{r, closers}, nil
+}
+
+// decodeElements calls fn for each <local> element in the metadata file.
+func decodeElements(
+	name string,
+	local string,
+	fn func(*xml.Decoder, *xml.StartElement) error,
+) error {
+	r, err := openMetadata(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == local {
+			if err = fn(dec, &se); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+		}
+	}
+}
+
+func loadPrimary(name string) ([]*Package, error) {
+	var pkgs []*Package
+	err := decodeElements(
+		name,
+		"package",
+		func(dec *xml.Decoder, se *xml.StartElement) error {
+			var p Package
+			if err := dec.DecodeElement(&p, se); err != nil {
+				return err
+			}
+			pkgs = append(pkgs, &p)
+			return nil
+		},
+	)
+	return pkgs, err
+}
+
+type filelistsPackage struct {
+	PkgID string   `xml:"pkgid,attr"`
+	Files []string `xml:"file"`
+}
+
+// loadFilelists adds the files in wanted to the packages in pkgs, which are
+// indexed by checksum.
+func loadFilelists(name string, pkgs map[string]*Package, wanted map[string]bool) error {
+	return decodeElements(
+		name,
+		"package",
+		func(dec *xml.Decoder, se *xml.StartElement) error {
+			var fp filelistsPackage
+			if err := dec.DecodeElement(&fp, se); err != nil {
+				return err
+			}
+			p, ok := pkgs[fp.PkgID]
+			if !ok {
+				return nil
+			}
+			for _, file := range fp.Files {
+				if wanted[file] {
+					p.Files = append(p.Files, file)
+				}
+			}
+			return nil
+		},
+	)
+}
+
+// LoadRepos loads the packages from the repositories in dirs.
+// Each directory should contain a repodata directory or be the repodata
+// directory itself.
+// Binary and source packages can be in the same or separate repositories.
+//
+// Only the files that other packages depend on are loaded from
+// filelists.xml.
+func LoadRepos(dirs []string) ([]*Package, error) {
+	var pkgs []*Package
+	repos := make([]*repo, 0, len(dirs))
+	wanted := map[string]bool{}
+	for _, dir := range dirs {
+		r, err := openRepo(dir)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, r)
+		if r.packages, err = loadPrimary(r.primary); err != nil {
+			return nil, err
+		}
+		for _, p := range r.packages {
+			for _, req := range p.Requires {
+				if strings.HasPrefix(req.Name, "/") {
+					wanted[req.Name] = true
+				}
+			}
+		}
+		pkgs = append(pkgs, r.packages...)
+	}
+
+	for _, r := range repos {
+		if r.filelists == "" {
+			continue
+		}
+		byChecksum := make(map[string]*Package, len(r.packages))
+		for _, p := range r.packages {
+			// The files from primary.xml are also in filelists.xml
+			p.Files = nil
+			byChecksum[p.Checksum] = p
+		}
+		if err := loadFilelists(r.filelists, byChecksum, wanted); err != nil {
+			return nil, err
+		}
+	}
+	return pkgs, nil
+}
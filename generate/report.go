@@ -0,0 +1,63 @@
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/report"
+)
+
+// WriteTXT writes the orphans.txt report using [report.TXTTemplate].
+func (r *Report) WriteTXT(w io.Writer) error {
+	return report.Render(w, report.TXTTemplate, report.NewData(r.Orphans, r.DepTree))
+}
+
+// writeTemp writes a temporary file in dir using fn and returns its name.
+func writeTemp(dir string, name string, fn func(io.Writer) error) (string, error) {
+	f, err := os.CreateTemp(dir, "."+name+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if err = fn(f); err == nil {
+		err = f.Chmod(0o644)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return f.Name(), nil
+}
+
+// WriteFiles writes orphans.json and orphans.txt to dir.
+// Both files are written to temporary files first so readers never see
+// files from different runs.
+func (r *Report) WriteFiles(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	jsonTmp, err := writeTemp(dir, common.OrphansJSON, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.Orphans)
+	})
+	if err != nil {
+		return err
+	}
+	defer os.Remove(jsonTmp)
+	txtTmp, err := writeTemp(dir, common.OrphansTXT, r.WriteTXT)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(txtTmp)
+	if err = os.Rename(jsonTmp, path.Join(dir, common.OrphansJSON)); err != nil {
+		return err
+	}
+	return os.Rename(txtTmp, path.Join(dir, common.OrphansTXT))
+}
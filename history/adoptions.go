@@ -0,0 +1,126 @@
+package history
+
+import (
+	"time"
+
+	"go.gtmx.me/goorphans/common"
+)
+
+// Adoption is a [common.Adoption] stored in the history database.
+type Adoption struct {
+	common.Adoption
+	// When the adopter was sent a confirmation mail
+	NotifiedAt *time.Time `json:"notified_at"`
+	// When the adoption was included in an announcement
+	AnnouncedAt *time.Time `json:"announced_at"`
+}
+
+// AddAdoptions stores adoptions in the database.
+// Adoptions that were already recorded are ignored.
+// It returns the number of new adoptions.
+func (s *Store) AddAdoptions(adoptions []common.Adoption) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO adoption (package, detected_at, admin, orphaned_at)
+		VALUES (?, ?, ?, ?);
+	`)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, a := range adoptions {
+		var orphanedAt *time.Time
+		if a.OrphanedAt != nil {
+			orphanedAt = common.Ptr(a.OrphanedAt.UTC())
+		}
+		res, err := stmt.Exec(a.Package, a.DetectedAt.UTC(), a.Admin, orphanedAt)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		added += int(n)
+	}
+	return added, tx.Commit()
+}
+
+func (s *Store) queryAdoptions(where string) ([]Adoption, error) {
+	var results []Adoption
+	rows, err := s.db.Query(`
+		SELECT package, detected_at, admin, orphaned_at, notified_at, announced_at
+		FROM adoption ` + where + `
+		ORDER BY detected_at, package;
+	`)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a Adoption
+		err = rows.Scan(
+			&a.Package, &a.DetectedAt, &a.Admin, &a.OrphanedAt,
+			&a.NotifiedAt, &a.AnnouncedAt,
+		)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, a)
+	}
+	return results, rows.Err()
+}
+
+// Adoptions returns all recorded adoptions, oldest first.
+func (s *Store) Adoptions() ([]Adoption, error) {
+	return s.queryAdoptions("")
+}
+
+// UnannouncedAdoptions returns the adoptions that haven't been included in an
+// announcement yet, oldest first.
+func (s *Store) UnannouncedAdoptions() ([]Adoption, error) {
+	return s.queryAdoptions("WHERE announced_at IS NULL")
+}
+
+// UnnotifiedAdoptions returns the adoptions whose adopters haven't been sent
+// a confirmation mail yet, oldest first.
+func (s *Store) UnnotifiedAdoptions() ([]Adoption, error) {
+	return s.queryAdoptions("WHERE notified_at IS NULL")
+}
+
+func (s *Store) markAdoptions(column string, adoptions []Adoption, t time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`
+		UPDATE adoption SET ` + column + ` = ?
+		WHERE package = ? AND detected_at = ?;
+	`)
+	if err != nil {
+		return err
+	}
+	for _, a := range adoptions {
+		if _, err = stmt.Exec(t.UTC(), a.Package, a.DetectedAt.UTC()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MarkNotified records that the adopters of adoptions were sent a
+// confirmation mail at t.
+func (s *Store) MarkNotified(adoptions []Adoption, t time.Time) error {
+	return s.markAdoptions("notified_at", adoptions, t)
+}
+
+// MarkAnnounced records that adoptions were included in an announcement sent
+// at t.
+func (s *Store) MarkAnnounced(adoptions []Adoption, t time.Time) error {
+	return s.markAdoptions("announced_at", adoptions, t)
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// maxReferences limits the number of Message-IDs in a References header.
+// The first announcement in the thread is always kept.
+const maxReferences = 10
+
+// Announcement is an orphans announcement that was sent.
+type Announcement struct {
+	MessageID string `json:"message_id"`
+	// Message-ID of the first announcement in the thread
+	ThreadID string    `json:"thread_id"`
+	Subject  string    `json:"subject"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// AddAnnouncement records an announcement.
+func (s *Store) AddAnnouncement(a Announcement) error {
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO announcement (message_id, thread_id, subject, sent_at)
+		VALUES (?, ?, ?, ?);
+	`, a.MessageID, a.ThreadID, a.Subject, a.SentAt.UTC())
+	return err
+}
+
+// LastAnnouncement returns the newest announcement or nil if none were
+// recorded.
+func (s *Store) LastAnnouncement() (*Announcement, error) {
+	var a Announcement
+	err := s.db.QueryRow(`
+		SELECT message_id, thread_id, subject, sent_at FROM announcement
+		ORDER BY sent_at DESC LIMIT 1;
+	`).Scan(&a.MessageID, &a.ThreadID, &a.Subject, &a.SentAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &a, err
+}
+
+// ThreadStart returns when the first announcement in threadID was sent.
+func (s *Store) ThreadStart(threadID string) (time.Time, error) {
+	var t time.Time
+	err := s.db.QueryRow(`
+		SELECT sent_at FROM announcement WHERE thread_id = ?
+		ORDER BY sent_at LIMIT 1;
+	`, threadID).Scan(&t)
+	return t, err
+}
+
+// References returns the Message-IDs for the References header of a reply to
+// the newest announcement in threadID, oldest first.
+// Long threads are truncated to the first announcement and the newest ones.
+func (s *Store) References(threadID string) ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT message_id FROM announcement WHERE thread_id = ?
+		ORDER BY sent_at;
+	`, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return ids, err
+	}
+	if len(ids) > maxReferences {
+		ids = append(ids[:1], ids[len(ids)-maxReferences+1:]...)
+	}
+	return ids, nil
+}
@@ -0,0 +1,292 @@
+// Package history keeps snapshots of the orphans data in a SQLite database so
+// past reports can be looked up after orphans.json has been overwritten.
+package history
+
+import (
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"go.gtmx.me/goorphans/common"
+)
+
+//go:embed schema.sql
+var schema string
+
+// ErrNoSnapshot is returned when no snapshot matches a query.
+var ErrNoSnapshot = errors.New("no matching snapshot in the history database")
+
+// Store is a history database of [common.Orphans] snapshots keyed by their
+// FinishedAt timestamp.
+type Store struct {
+	db *sql.DB
+}
+
+// Snapshot summarizes a stored [common.Orphans] dataset.
+type Snapshot struct {
+	FinishedAt time.Time
+	StartedAt  *time.Time
+	Orphans    int
+	Breaking   int
+}
+
+func Open(filename string) (*Store, error) {
+	db, err := sql.Open("sqlite3", filename+"?_foreign_keys=1")
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(schema)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize history database: %w", err)
+	}
+	return &Store{db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add stores o in the database.
+// It returns false if a snapshot with the same FinishedAt already exists.
+func (s *Store) Add(o *common.Orphans) (bool, error) {
+	if o.FinishedAt == nil {
+		return false, fmt.Errorf("cannot record orphans data without finished_at")
+	}
+	key := o.FinishedAt.UTC()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var startedAt *time.Time
+	if o.StartedAt != nil {
+		startedAt = common.Ptr(o.StartedAt.UTC())
+	}
+	res, err := tx.Exec(`
+		INSERT OR IGNORE INTO snapshot (finished_at, started_at, record_time)
+		VALUES (?, ?, unixepoch('now','subsec'));
+	`, key, startedAt)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	orphans := mapset.NewThreadUnsafeSet(o.Orphans...)
+	categories := o.PackageCategories()
+	packages := orphans.Clone()
+	packages.Append(slices.Collect(maps.Keys(categories))...)
+
+	pkgstmt, err := tx.Prepare(`
+		INSERT INTO snapshot_package (finished_at, package, orphaned, status_change)
+		VALUES (?, ?, ?, ?);
+	`)
+	if err != nil {
+		return false, err
+	}
+	catstmt, err := tx.Prepare(`
+		INSERT INTO snapshot_category (finished_at, package, category)
+		VALUES (?, ?, ?);
+	`)
+	if err != nil {
+		return false, err
+	}
+	for pkg := range mapset.Elements(packages) {
+		var statusChange *time.Time
+		if t, ok := o.StatusChange[pkg]; ok {
+			statusChange = common.Ptr(t.UTC())
+		}
+		_, err = pkgstmt.Exec(key, pkg, orphans.Contains(pkg), statusChange)
+		if err != nil {
+			return false, err
+		}
+		for _, c := range categories[pkg] {
+			if _, err = catstmt.Exec(key, pkg, string(c)); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	personstmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO snapshot_person (finished_at, person, package, direct)
+		VALUES (?, ?, ?, ?);
+	`)
+	if err != nil {
+		return false, err
+	}
+	for person, pkgs := range o.AllAffectedPeople {
+		direct := mapset.NewThreadUnsafeSet(o.AffectedPeople[person]...)
+		for _, pkg := range pkgs {
+			if _, err = personstmt.Exec(key, person, pkg, direct.Contains(pkg)); err != nil {
+				return false, err
+			}
+		}
+	}
+	// Direct maintainers should also be listed in all_affected_people, but
+	// don't rely on it.
+	for person, pkgs := range o.AffectedPeople {
+		for _, pkg := range pkgs {
+			if _, err = personstmt.Exec(key, person, pkg, true); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, tx.Commit()
+}
+
+// List returns a summary of each stored snapshot, oldest first.
+func (s *Store) List() ([]Snapshot, error) {
+	var results []Snapshot
+	rows, err := s.db.Query(`
+		SELECT
+			s.finished_at,
+			s.started_at,
+			(SELECT count(*) FROM snapshot_package p
+				WHERE p.finished_at = s.finished_at AND p.orphaned),
+			(SELECT count(DISTINCT c.package) FROM snapshot_category c
+				WHERE c.finished_at = s.finished_at AND c.category IN (?, ?))
+		FROM snapshot s
+		ORDER BY s.finished_at;
+	`, common.CategoryOrphansBreakingDeps, common.CategoryOrphansBreakingDepsStale)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var snap Snapshot
+		err = rows.Scan(&snap.FinishedAt, &snap.StartedAt, &snap.Orphans, &snap.Breaking)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, snap)
+	}
+	return results, rows.Err()
+}
+
+// FindBefore returns the FinishedAt key of the newest snapshot that finished
+// at or before t.
+// It returns [ErrNoSnapshot] if there is no such snapshot.
+func (s *Store) FindBefore(t time.Time) (time.Time, error) {
+	var key time.Time
+	err := s.db.QueryRow(`
+		SELECT finished_at FROM snapshot
+		WHERE finished_at <= ?
+		ORDER BY finished_at DESC LIMIT 1;
+	`, t.UTC()).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return key, ErrNoSnapshot
+	}
+	return key, err
+}
+
+// Latest returns the FinishedAt key of the newest snapshot.
+// It returns [ErrNoSnapshot] if the database is empty.
+func (s *Store) Latest() (time.Time, error) {
+	var key time.Time
+	err := s.db.QueryRow(`
+		SELECT finished_at FROM snapshot ORDER BY finished_at DESC LIMIT 1;
+	`).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return key, ErrNoSnapshot
+	}
+	return key, err
+}
+
+// Get reconstructs the [common.Orphans] data stored for the snapshot that
+// finished at finishedAt.
+// Only the fields stored in the history database are populated.
+func (s *Store) Get(finishedAt time.Time) (*common.Orphans, error) {
+	key := finishedAt.UTC()
+	o := &common.Orphans{
+		AffectedPeople:    map[string][]string{},
+		AllAffectedPeople: map[string][]string{},
+		StatusChange:      map[string]time.Time{},
+		FinishedAt:        &key,
+	}
+	err := s.db.QueryRow(
+		`SELECT started_at FROM snapshot WHERE finished_at = ?;`, key,
+	).Scan(&o.StartedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoSnapshot
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT package, orphaned, status_change FROM snapshot_package
+		WHERE finished_at = ? ORDER BY package;
+	`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pkg string
+		var orphaned bool
+		var statusChange *time.Time
+		if err = rows.Scan(&pkg, &orphaned, &statusChange); err != nil {
+			return nil, err
+		}
+		if orphaned {
+			o.Orphans = append(o.Orphans, pkg)
+		}
+		if statusChange != nil {
+			o.StatusChange[pkg] = *statusChange
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	crows, err := s.db.Query(`
+		SELECT package, category FROM snapshot_category
+		WHERE finished_at = ? ORDER BY package;
+	`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer crows.Close()
+	for crows.Next() {
+		var pkg string
+		var c common.Category
+		if err = crows.Scan(&pkg, &c); err != nil {
+			return nil, err
+		}
+		if list := o.CategoryPackages(c); list != nil {
+			*list = append(*list, pkg)
+		}
+	}
+	if err = crows.Err(); err != nil {
+		return nil, err
+	}
+
+	prows, err := s.db.Query(`
+		SELECT person, package, direct FROM snapshot_person
+		WHERE finished_at = ? ORDER BY person, package;
+	`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer prows.Close()
+	for prows.Next() {
+		var person, pkg string
+		var direct bool
+		if err = prows.Scan(&person, &pkg, &direct); err != nil {
+			return nil, err
+		}
+		o.AllAffectedPeople[person] = append(o.AllAffectedPeople[person], pkg)
+		if direct {
+			o.AffectedPeople[person] = append(o.AffectedPeople[person], pkg)
+		}
+	}
+	return o, prows.Err()
+}
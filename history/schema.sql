@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS snapshot (
+    finished_at TIMESTAMP PRIMARY KEY,
+    started_at TIMESTAMP,
+    record_time REAL NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS snapshot_package (
+    finished_at TIMESTAMP,
+    package TEXT,
+    orphaned INTEGER NOT NULL,
+    status_change TIMESTAMP,
+    FOREIGN KEY (finished_at) REFERENCES snapshot(finished_at) ON DELETE CASCADE,
+    PRIMARY KEY (finished_at, package)
+);
+
+CREATE TABLE IF NOT EXISTS snapshot_category (
+    finished_at TIMESTAMP,
+    package TEXT,
+    category TEXT,
+    FOREIGN KEY (finished_at) REFERENCES snapshot(finished_at) ON DELETE CASCADE,
+    PRIMARY KEY (finished_at, package, category)
+);
+
+CREATE TABLE IF NOT EXISTS snapshot_person (
+    finished_at TIMESTAMP,
+    person TEXT,
+    package TEXT,
+    direct INTEGER NOT NULL,
+    FOREIGN KEY (finished_at) REFERENCES snapshot(finished_at) ON DELETE CASCADE,
+    PRIMARY KEY (finished_at, person, package)
+);
+
+CREATE TABLE IF NOT EXISTS adoption (
+    package TEXT,
+    detected_at TIMESTAMP,
+    admin TEXT NOT NULL,
+    orphaned_at TIMESTAMP,
+    notified_at TIMESTAMP,
+    announced_at TIMESTAMP,
+    PRIMARY KEY (package, detected_at)
+);
+
+CREATE TABLE IF NOT EXISTS announcement (
+    message_id TEXT PRIMARY KEY,
+    thread_id TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    sent_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"fmt"
+	"strings"
+
+	gomail "github.com/wneessen/go-mail"
+)
+
+// batchMessageID derives the Message-ID of batch i (starting at zero) of n
+// from msgid.
+// For example, <abc@example.com> becomes <abc.b1of3@example.com>.
+func batchMessageID(msgid string, i, n int) string {
+	msgid = strings.Trim(msgid, "<>")
+	idx := strings.LastIndex(msgid, "@")
+	if idx == -1 {
+		idx = len(msgid)
+	}
+	return fmt.Sprintf("%s.b%dof%d%s", msgid[:idx], i+1, n, msgid[idx:])
+}
+
+// splitBCC renders a message that was finalized with [FinalizeMsg] into
+// envelopes with at most size Bcc recipients each.
+// All batches have the same headers, but the To and Cc recipients only
+// receive the first one.
+// Each batch's Message-ID is derived from the original Message-ID with
+// [batchMessageID] so they can be identified as a single logical message.
+// Afterwards, msg has the first batch's Message-ID.
+// If size is zero or the message has no more than size Bcc recipients,
+// a single envelope is returned.
+func splitBCC(msg *gomail.Msg, size int) ([]*Envelope, error) {
+	bcc := msg.GetBcc()
+	if size <= 0 || len(bcc) <= size {
+		e, err := NewEnvelope(msg)
+		if err != nil {
+			return nil, err
+		}
+		return []*Envelope{e}, nil
+	}
+	n := (len(bcc) + size - 1) / size
+	// Restore the Bcc recipients when we're done.
+	// The Message-ID is left as the first batch's, which is the one the To and
+	// Cc recipients receive.
+	msgid := msg.GetMessageID()
+	defer func() {
+		msg.BccMailAddress(bcc...)
+		msg.SetMessageIDWithValue(batchMessageID(msgid, 0, n))
+	}()
+
+	visible := len(msg.GetTo()) + len(msg.GetCc())
+	envelopes := make([]*Envelope, 0, n)
+	for i := range n {
+		msg.BccMailAddress(bcc[i*size : min((i+1)*size, len(bcc))]...)
+		msg.SetMessageIDWithValue(batchMessageID(msgid, i, n))
+		e, err := NewEnvelope(msg)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			e.Recipients = e.Recipients[visible:]
+		}
+		envelopes = append(envelopes, e)
+	}
+	return envelopes, nil
+}
@@ -0,0 +1,211 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"maps"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Reasons for including a recipient passed to [DryRun.AddRecipients]
+const (
+	ReasonTo          = "To"
+	ReasonMaintainer  = "direct maintainer"
+	ReasonGroupMember = "group member"
+	ReasonConfigBCC   = "config BCC"
+	ReasonOther       = "other"
+)
+
+type dryRunKeyType struct{}
+
+var dryRunKey = dryRunKeyType{}
+
+// DryRun prints the messages that would be sent and a report of their
+// recipients instead of sending them.
+// Pass it to [SendMsg] with [WithDryRun].
+type DryRun struct {
+	// Number of body lines to print for each message.
+	// 0 prints the whole body.
+	Lines int
+	// Defaults to os.Stdout
+	Out io.Writer
+	// address -> reason
+	reasons map[string]string
+	// name -> error
+	unresolved map[string]error
+}
+
+// WithDryRun returns a copy of ctx that makes [SendMsg] print messages with d
+// instead of sending them.
+func WithDryRun(ctx context.Context, d *DryRun) context.Context {
+	return context.WithValue(ctx, dryRunKey, d)
+}
+
+// DryRunFrom returns the [DryRun] in ctx or nil if this isn't a dry run.
+func DryRunFrom(ctx context.Context) *DryRun {
+	d, _ := ctx.Value(dryRunKey).(*DryRun)
+	return d
+}
+
+// normalizeAddress returns the bare, lowercase address from addr, which may
+// include a display name or angle brackets.
+func normalizeAddress(addr string) string {
+	if a, err := netmail.ParseAddress(addr); err == nil {
+		addr = a.Address
+	}
+	return strings.ToLower(strings.Trim(addr, "<>"))
+}
+
+// AddRecipients records why addrs were included.
+// The first reason recorded for an address wins.
+// It's a no-op if d is nil, so callers don't need to check for a dry run.
+func (d *DryRun) AddRecipients(reason string, addrs ...string) {
+	if d == nil {
+		return
+	}
+	if d.reasons == nil {
+		d.reasons = make(map[string]string)
+	}
+	for _, addr := range addrs {
+		addr = normalizeAddress(addr)
+		if _, ok := d.reasons[addr]; !ok {
+			d.reasons[addr] = reason
+		}
+	}
+}
+
+// AddUnresolved records a user or @group whose address couldn't be resolved.
+// It's a no-op if d is nil.
+func (d *DryRun) AddUnresolved(name string, err error) {
+	if d == nil {
+		return
+	}
+	if d.unresolved == nil {
+		d.unresolved = make(map[string]error)
+	}
+	d.unresolved[name] = err
+}
+
+func (d *DryRun) out() io.Writer {
+	if d.Out == nil {
+		return os.Stdout
+	}
+	return d.Out
+}
+
+// Show prints envelopes followed by the recipient report.
+func (d *DryRun) Show(envelopes []*Envelope) error {
+	w := bufio.NewWriter(d.out())
+	for i, e := range envelopes {
+		if err := d.writeEnvelope(w, i, len(envelopes), e); err != nil {
+			return err
+		}
+	}
+	if err := d.writeReport(w, envelopes); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (d *DryRun) writeEnvelope(w io.Writer, i, n int, e *Envelope) error {
+	header, _, _ := bytes.Cut(e.Data, []byte("\r\n\r\n"))
+	_, err := fmt.Fprintf(
+		w, "==> Message %d/%d\n%s\nEnvelope-From: %s\nEnvelope-Recipients: %d\n\n",
+		i+1, n, bytes.ReplaceAll(header, []byte("\r\n"), []byte("\n")),
+		e.From, len(e.Recipients),
+	)
+	if err != nil {
+		return err
+	}
+	msg, err := netmail.ReadMessage(bytes.NewReader(e.Data))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", e.MessageID, err)
+	}
+	body := msg.Body
+	switch strings.ToLower(msg.Header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	scanner := bufio.NewScanner(body)
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		if d.Lines > 0 && lines > d.Lines {
+			continue
+		}
+		if _, err = fmt.Fprintln(w, scanner.Text()); err != nil {
+			return err
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	if d.Lines > 0 && lines > d.Lines {
+		if _, err = fmt.Fprintf(w, "[... %d more lines]\n", lines-d.Lines); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+func (d *DryRun) writeReport(w io.Writer, envelopes []*Envelope) error {
+	groups := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, e := range envelopes {
+		for _, rcpt := range e.Recipients {
+			addr := normalizeAddress(rcpt)
+			if seen[addr] {
+				continue
+			}
+			seen[addr] = true
+			reason, ok := d.reasons[addr]
+			if !ok {
+				reason = ReasonOther
+			}
+			groups[reason] = append(groups[reason], addr)
+		}
+	}
+	_, err := fmt.Fprintf(
+		w, "==> Dry run: %d messages to %d unique recipients (nothing was sent)\n",
+		len(envelopes), len(seen),
+	)
+	if err != nil {
+		return err
+	}
+	for _, reason := range slices.Sorted(maps.Keys(groups)) {
+		addrs := groups[reason]
+		slices.Sort(addrs)
+		if _, err = fmt.Fprintf(w, "%s (%d):\n", reason, len(addrs)); err != nil {
+			return err
+		}
+		for _, addr := range addrs {
+			if _, err = fmt.Fprintf(w, "  %s\n", addr); err != nil {
+				return err
+			}
+		}
+	}
+	if len(d.unresolved) == 0 {
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "Failed to resolve (%d):\n", len(d.unresolved))
+	if err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(d.unresolved)) {
+		if _, err = fmt.Fprintf(w, "  %s: %v\n", name, d.unresolved[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
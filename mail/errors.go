@@ -0,0 +1,125 @@
+package mail
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// ErrorClass describes how a delivery failure should be handled.
+type ErrorClass int
+
+const (
+	// The server returned a 4xx reply or the connection failed.
+	// The message can be retried later.
+	ErrTemporary ErrorClass = iota
+	// The server returned a 5xx reply or delivery failed for another reason,
+	// such as the configuration, that retrying won't help.
+	ErrPermanent
+	// The server rejected all of the message's recipients.
+	// Other messages can still be sent.
+	ErrRecipientRejected
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrTemporary:
+		return "temporary"
+	case ErrPermanent:
+		return "permanent"
+	case ErrRecipientRejected:
+		return "recipient rejected"
+	}
+	return fmt.Sprintf("ErrorClass(%d)", int(c))
+}
+
+// SendError is returned by [Transport.Send] when delivery fails.
+type SendError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *SendError) Error() string {
+	if e.Class == ErrRecipientRejected {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s failure: %v", e.Class, e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// classify wraps err in a [SendError].
+// SMTP replies are classified by their code.
+// Network errors, such as a dropped connection, are temporary, except for
+// host lookups and certificate verification that failed because of the
+// configuration.
+// Other errors, such as failing to open a local mailbox, are permanent.
+func classify(err error) *SendError {
+	var serr *SendError
+	if errors.As(err, &serr) {
+		return serr
+	}
+	return &SendError{errorClass(err), err}
+}
+
+func errorClass(err error) ErrorClass {
+	var terr *textproto.Error
+	if errors.As(err, &terr) {
+		if terr.Code >= 500 {
+			return ErrPermanent
+		}
+		return ErrTemporary
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return ErrPermanent
+	}
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return ErrPermanent
+	}
+	// Not net.Error, which is also implemented by syscall.Errno from local
+	// file operations
+	var opErr *net.OpError
+	if errors.As(err, &opErr) ||
+		errors.Is(err, os.ErrDeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTemporary
+	}
+	return ErrPermanent
+}
+
+// ErrorClassOf returns the [ErrorClass] of an error returned by
+// [Transport.Send].
+func ErrorClassOf(err error) ErrorClass {
+	return classify(err).Class
+}
+
+// isReply reports whether err is a reply from the server, which means the
+// SMTP session is still usable.
+func isReply(err error) bool {
+	var terr *textproto.Error
+	return errors.As(err, &terr)
+}
+
+// RejectedRecipient is a recipient that the server refused.
+type RejectedRecipient struct {
+	Address string
+	Err     error
+}
+
+func formatRejected(rejected []RejectedRecipient) string {
+	s := make([]string, 0, len(rejected))
+	for _, r := range rejected {
+		s = append(s, fmt.Sprintf("%s (%v)", r.Address, r.Err))
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// toUnix converts a rendered message's CRLF line endings to LF, as local
+// mailbox formats expect.
+func toUnix(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+}
+
+// mboxTransport appends messages to an mbox file using the mboxrd format.
+type mboxTransport struct {
+	path string
+}
+
+func (t *mboxTransport) Send(_ context.Context, e *Envelope) (string, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(
+		&b, "From %s %s\n",
+		strings.Trim(e.From, "<>"), time.Now().UTC().Format(time.ANSIC),
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(toUnix(e.Data)))
+	scanner.Buffer(nil, len(e.Data)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		// mboxrd: quote "From " lines, including ones that are already quoted
+		if bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) {
+			b.WriteByte('>')
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	b.WriteByte('\n')
+
+	f, err := os.OpenFile(t.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err = f.Write(b.Bytes()); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+	return "appended to " + t.path, nil
+}
+
+func (t *mboxTransport) Close() error {
+	return nil
+}
+
+// maildirTransport delivers messages into a Maildir's new directory.
+type maildirTransport struct {
+	dir string
+}
+
+var maildirCounter atomic.Int64
+
+// maildirName returns a unique file name for a new message.
+// See https://cr.yp.to/proto/maildir.html.
+func maildirName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	// "/" and ":" are not allowed in the name
+	hostname = strings.NewReplacer("/", `\057`, ":", `\072`).Replace(hostname)
+	now := time.Now()
+	return fmt.Sprintf(
+		"%d.M%dP%dQ%d.%s",
+		now.Unix(), now.Nanosecond()/1000, os.Getpid(), maildirCounter.Add(1), hostname,
+	)
+}
+
+func (t *maildirTransport) Send(_ context.Context, e *Envelope) (string, error) {
+	name := maildirName()
+	tmp := filepath.Join(t.dir, "tmp", name)
+	if err := os.WriteFile(tmp, toUnix(e.Data), 0o644); err != nil {
+		return "", err
+	}
+	dest := filepath.Join(t.dir, "new", name)
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return "delivered to " + dest, nil
+}
+
+func (t *maildirTransport) Close() error {
+	return nil
+}
@@ -5,13 +5,12 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"log"
 	"os"
-	"path"
 	"strings"
 	"time"
 
 	gomail "github.com/wneessen/go-mail"
-	"github.com/wneessen/go-mail/smtp"
 	"go.gtmx.me/goorphans/config"
 )
 
@@ -56,6 +55,11 @@ func FinalizeMsg(config *config.SMTPConfig, msg *gomail.Msg) error {
 	if err != nil {
 		return err
 	}
+	if config.EnvelopeFrom != "" {
+		if err = msg.EnvelopeFrom(config.EnvelopeFrom); err != nil {
+			return err
+		}
+	}
 	// Get the parsed From value
 	from := msg.GetFrom()
 	msgid, err := getMsgID(from[0].Address)
@@ -79,47 +83,85 @@ func MsgSetBodyFromFile(msg *gomail.Msg, name string) error {
 	return nil
 }
 
+// SendMsg finalizes and sends msgs.
+// Messages whose recipients are all rejected are logged and skipped.
+// Other failures stop the run once the configured retries are exhausted.
+// If ctx has a [DryRun], the messages are printed instead of being sent.
+// Messages with more than smtp.bcc-batch-size Bcc recipients are split into
+// multiple messages.
+// If smtp.queue is enabled, the messages are added to the outbox first so
+// an interrupted run can be continued with [Outbox.Flush].
 func SendMsg(ctx context.Context, config *config.Config, msgs ...*gomail.Msg) error {
-	var c *gomail.Client
-	var sclient *smtp.Client
-	var err error
-	if config.SMTP.OutgoingDir == "" {
-		c, err = NewClient(&config.SMTP)
-		if err != nil {
-			return err
-		}
-		sclient, err = c.DialToSMTPClientWithContext(ctx)
-		if err != nil {
-			return err
-		}
-		defer c.CloseWithSMTPClient(sclient)
-	}
 	// FinalizeMsgs before we begin sending in case there's an error.
+	envelopes := make([]*Envelope, 0, len(msgs))
 	for i, msg := range msgs {
-		err = FinalizeMsg(&config.SMTP, msg)
+		err := FinalizeMsg(&config.SMTP, msg)
 		if err != nil {
 			return fmt.Errorf("failed to finalize msg at index %v: %w", i, err)
 		}
-	}
-	for i, msg := range msgs {
-		err := FinalizeMsg(&config.SMTP, msg)
+		batches, err := splitBCC(msg, config.SMTP.BCCBatchSize)
 		if err != nil {
 			return err
 		}
-		r, _ := msg.GetRecipients()
+		envelopes = append(envelopes, batches...)
+	}
+	if d := DryRunFrom(ctx); d != nil {
+		return d.Show(envelopes)
+	}
+	t, err := NewTransport(&config.SMTP)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	if config.SMTP.Queue {
+		return sendQueued(ctx, config.SMTP.QueueDB, t, envelopes)
+	}
+	for i, e := range envelopes {
 		fmt.Printf(
 			"(%d/%d) Sending %q to %d recipients...\n",
-			i+1, len(msgs), msg.GetGenHeader("Subject")[0], len(r),
+			i+1, len(envelopes), e.Subject, len(e.Recipients),
 		)
-		if config.SMTP.OutgoingDir == "" {
-			err = c.SendWithSMTPClient(sclient, msg)
-		} else {
-			p := path.Join(config.SMTP.OutgoingDir, msg.GetMessageID()+".eml")
-			err = msg.WriteToFile(p)
-		}
-		if err != nil {
-			return err
+		if _, err := t.Send(ctx, e); err != nil {
+			if ErrorClassOf(err) != ErrRecipientRejected {
+				return fmt.Errorf("failed to send %s: %w", e.MessageID, err)
+			}
+			log.Printf("skipping %s: %v", e.MessageID, err)
 		}
 	}
 	return nil
 }
+
+// sendQueued adds envelopes to the outbox at dbPath and flushes it.
+// It refuses to queue more messages while messages from a previous run
+// haven't been sent.
+func sendQueued(
+	ctx context.Context,
+	dbPath string,
+	t Transport,
+	envelopes []*Envelope,
+) error {
+	outbox, err := OpenOutbox(dbPath)
+	if err != nil {
+		return err
+	}
+	defer outbox.Close()
+	if _, err = outbox.Recover(); err != nil {
+		return err
+	}
+	unsent, err := outbox.List(StatusQueued, StatusFailed, StatusInterrupted)
+	if err != nil {
+		return err
+	}
+	if len(unsent) > 0 {
+		return fmt.Errorf(
+			"the outbox at %s has %d unsent messages from a previous run;"+
+				" flush, retry, or drop them first",
+			dbPath, len(unsent),
+		)
+	}
+	if err = outbox.Enqueue(envelopes...); err != nil {
+		return err
+	}
+	_, err = outbox.Flush(ctx, t)
+	return err
+}
@@ -0,0 +1,298 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//go:embed outbox.sql
+var outboxSchema string
+
+// Status is the delivery status of a message in the [Outbox].
+type Status string
+
+const (
+	StatusQueued Status = "queued"
+	// The message is being sent
+	StatusSending Status = "sending"
+	StatusSent    Status = "sent"
+	StatusFailed  Status = "failed"
+	// The server rejected all of the message's recipients
+	StatusRejected Status = "rejected"
+	// The message was being sent when a previous run was interrupted, so it
+	// may have been delivered
+	StatusInterrupted Status = "interrupted"
+)
+
+// Statuses lists every [Status].
+var Statuses = []Status{
+	StatusQueued,
+	StatusSending,
+	StatusSent,
+	StatusFailed,
+	StatusRejected,
+	StatusInterrupted,
+}
+
+func (s Status) String() string {
+	return string(s)
+}
+
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+func (s *Status) UnmarshalText(text []byte) error {
+	for _, status := range Statuses {
+		if string(text) == string(status) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid status: %q", text)
+}
+
+// ErrInterrupted is the response recorded for messages that were being sent
+// when a previous run was interrupted.
+var ErrInterrupted = errors.New(
+	"interrupted while sending; the message may have been delivered",
+)
+
+// QueuedMessage is a message in the [Outbox].
+// Envelope.Data is not loaded.
+type QueuedMessage struct {
+	Envelope
+	Status Status `json:"status"`
+	// The server response or error from the last attempt
+	Response  string    `json:"response"`
+	Attempts  int       `json:"attempts"`
+	QueuedAt  time.Time `json:"queued_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Outbox is a persistent queue of rendered messages.
+// Each message is marked as sent or failed once it was attempted so an
+// interrupted run can be continued without sending duplicates.
+type Outbox struct {
+	db *sql.DB
+}
+
+func OpenOutbox(filename string) (*Outbox, error) {
+	db, err := sql.Open("sqlite3", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = db.Exec(outboxSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize outbox database: %w", err)
+	}
+	return &Outbox{db}, nil
+}
+
+func (o *Outbox) Close() error {
+	return o.db.Close()
+}
+
+// Enqueue adds envelopes to the queue.
+func (o *Outbox) Enqueue(envelopes ...*Envelope) error {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`
+		INSERT INTO message
+			(message_id, subject, sender, recipients, data, status, queued_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+	`)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, e := range envelopes {
+		_, err = stmt.Exec(
+			e.MessageID, e.Subject, e.From, strings.Join(e.Recipients, "\n"), e.Data,
+			StatusQueued, now, now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to queue %s: %w", e.MessageID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// List returns the messages with any of statuses or all messages if statuses
+// is empty, in the order they were queued.
+func (o *Outbox) List(statuses ...Status) ([]QueuedMessage, error) {
+	query := `
+		SELECT message_id, subject, sender, recipients, status, response, attempts,
+			queued_at, updated_at
+		FROM message`
+	var args []any
+	if len(statuses) > 0 {
+		query += " WHERE status IN (?" + strings.Repeat(", ?", len(statuses)-1) + ")"
+		for _, s := range statuses {
+			args = append(args, s)
+		}
+	}
+	rows, err := o.db.Query(query+" ORDER BY rowid;", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []QueuedMessage
+	for rows.Next() {
+		var m QueuedMessage
+		var recipients string
+		err = rows.Scan(
+			&m.MessageID, &m.Subject, &m.From, &recipients, &m.Status, &m.Response,
+			&m.Attempts, &m.QueuedAt, &m.UpdatedAt,
+		)
+		if err != nil {
+			return results, err
+		}
+		m.Recipients = strings.Split(recipients, "\n")
+		results = append(results, m)
+	}
+	return results, rows.Err()
+}
+
+// Load returns m's envelope along with the rendered message.
+func (o *Outbox) Load(m QueuedMessage) (*Envelope, error) {
+	e := m.Envelope
+	err := o.db.QueryRow(
+		`SELECT data FROM message WHERE message_id = ?;`, e.MessageID,
+	).Scan(&e.Data)
+	return &e, err
+}
+
+func (o *Outbox) setStatus(id string, status Status, response string) error {
+	_, err := o.db.Exec(`
+		UPDATE message SET status = ?, response = ?, updated_at = ?
+		WHERE message_id = ?;
+	`, status, response, time.Now().UTC(), id)
+	return err
+}
+
+// Recover marks messages that were being sent when a previous run was
+// interrupted with [StatusInterrupted].
+// They are not retried automatically, as they may have been delivered.
+func (o *Outbox) Recover() (int, error) {
+	res, err := o.db.Exec(`
+		UPDATE message SET status = ?, response = ?, updated_at = ?
+		WHERE status = ?;
+	`, StatusInterrupted, ErrInterrupted.Error(), time.Now().UTC(), StatusSending)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Flush sends the queued messages with t in the order they were queued.
+// Messages whose recipients are all rejected are marked as rejected and
+// skipped.
+// Flush stops at any other failure, which is recorded along with the response
+// from the server.
+// It returns the number of messages that were sent.
+func (o *Outbox) Flush(ctx context.Context, t Transport) (int, error) {
+	queued, err := o.List(StatusQueued)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for i, m := range queued {
+		if err = ctx.Err(); err != nil {
+			return sent, err
+		}
+		e, err := o.Load(m)
+		if err != nil {
+			return sent, err
+		}
+		_, err = o.db.Exec(`
+			UPDATE message SET status = ?, attempts = attempts + 1, updated_at = ?
+			WHERE message_id = ?;
+		`, StatusSending, time.Now().UTC(), e.MessageID)
+		if err != nil {
+			return sent, err
+		}
+		fmt.Printf(
+			"(%d/%d) Sending %q to %d recipients...\n",
+			i+1, len(queued), e.Subject, len(e.Recipients),
+		)
+		response, err := t.Send(ctx, e)
+		if err != nil && ErrorClassOf(err) == ErrRecipientRejected {
+			log.Printf("skipping %s: %v", e.MessageID, err)
+			if err = o.setStatus(e.MessageID, StatusRejected, err.Error()); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		if err != nil {
+			if serr := o.setStatus(e.MessageID, StatusFailed, err.Error()); serr != nil {
+				err = errors.Join(err, serr)
+			}
+			return sent, fmt.Errorf("failed to send %s: %w", e.MessageID, err)
+		}
+		if err = o.setStatus(e.MessageID, StatusSent, response); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Retry queues the failed messages with the given Message-IDs again or all
+// failed messages if ids is empty.
+// Interrupted messages are only queued again if they're listed in ids or
+// interrupted is true, since they may have been delivered.
+// It returns the number of requeued messages.
+func (o *Outbox) Retry(interrupted bool, ids ...string) (int, error) {
+	query := `UPDATE message SET status = ?, updated_at = ? WHERE status = ?`
+	args := []any{StatusQueued, time.Now().UTC(), StatusFailed}
+	if interrupted || len(ids) > 0 {
+		query = `UPDATE message SET status = ?, updated_at = ? WHERE status IN (?, ?)`
+		args = append(args, StatusInterrupted)
+	}
+	return o.update(query, args, ids)
+}
+
+// Drop removes the messages with the given Message-IDs.
+// It returns the number of removed messages.
+func (o *Outbox) Drop(ids ...string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return o.update(`DELETE FROM message WHERE 1`, nil, ids)
+}
+
+// DropStatus removes all messages with status.
+// It returns the number of removed messages.
+func (o *Outbox) DropStatus(status Status) (int, error) {
+	return o.update(`DELETE FROM message WHERE status = ?`, []any{status}, nil)
+}
+
+// update runs query, which must end with a WHERE clause, on the messages with
+// the given Message-IDs or all matching messages if ids is empty.
+func (o *Outbox) update(query string, args []any, ids []string) (int, error) {
+	if len(ids) > 0 {
+		query += " AND message_id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	res, err := o.db.Exec(query+";", args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
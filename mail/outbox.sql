@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS message (
+    message_id TEXT PRIMARY KEY,
+    subject TEXT NOT NULL,
+    sender TEXT NOT NULL,
+    -- One address per line
+    recipients TEXT NOT NULL,
+    data BLOB NOT NULL,
+    -- queued, sending, sent, failed, rejected, or interrupted
+    status TEXT NOT NULL,
+    -- The server response or error from the last attempt
+    response TEXT NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    queued_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Exit codes from sysexits.h that sendmail implementations use
+const (
+	exNoUser   = 67
+	exTempFail = 75
+)
+
+// sendmailTransport pipes messages to a sendmail-compatible command.
+// If the command has the -t flag, the MTA reads the recipients from the
+// message headers, so a Bcc header is added that the MTA removes before
+// delivery.
+// Otherwise, the recipients are passed as arguments.
+type sendmailTransport struct {
+	cmd         []string
+	fromHeaders bool
+}
+
+func newSendmailTransport(cmd []string) *sendmailTransport {
+	return &sendmailTransport{cmd, slices.Contains(cmd[1:], "-t")}
+}
+
+func (t *sendmailTransport) Send(ctx context.Context, e *Envelope) (string, error) {
+	args := slices.Clone(t.cmd[1:])
+	args = append(args, "-f", strings.Trim(e.From, "<>"))
+	data := e.Data
+	if t.fromHeaders {
+		var err error
+		if data, err = withBccHeader(e); err != nil {
+			return "", &SendError{ErrPermanent, err}
+		}
+	} else {
+		args = append(args, "--")
+		for _, rcpt := range e.Recipients {
+			args = append(args, strings.Trim(rcpt, "<>"))
+		}
+	}
+
+	c := exec.CommandContext(ctx, t.cmd[0], args...)
+	c.Stdin = bytes.NewReader(data)
+	var output bytes.Buffer
+	c.Stdout = &output
+	c.Stderr = &output
+	err := c.Run()
+	out := strings.TrimSpace(output.String())
+	if err == nil {
+		if out == "" {
+			out = "accepted by " + t.cmd[0]
+		}
+		return out, nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return "", &SendError{
+			ErrPermanent,
+			fmt.Errorf("failed to run %s: %w", t.cmd[0], err),
+		}
+	}
+	class := ErrPermanent
+	switch exitErr.ExitCode() {
+	case exTempFail:
+		class = ErrTemporary
+	case exNoUser:
+		class = ErrRecipientRejected
+	}
+	if out != "" {
+		err = fmt.Errorf("%w: %s", err, out)
+	}
+	return "", &SendError{class, fmt.Errorf("%s: %w", t.cmd[0], err)}
+}
+
+func (t *sendmailTransport) Close() error {
+	return nil
+}
+
+// withBccHeader adds a Bcc header with e's recipients that aren't in the To
+// or Cc headers.
+// It fails if any of the To or Cc addresses aren't recipients of e, as is the
+// case for every batch but the first one created by [splitBCC], because
+// sendmail -t would deliver to them anyway.
+func withBccHeader(e *Envelope) ([]byte, error) {
+	msg, err := netmail.ReadMessage(bytes.NewReader(e.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", e.MessageID, err)
+	}
+	recipients := make(map[string]bool, len(e.Recipients))
+	for _, rcpt := range e.Recipients {
+		recipients[normalizeAddress(rcpt)] = true
+	}
+	for _, key := range []string{"To", "Cc"} {
+		addrs, err := msg.Header.AddressList(key)
+		if err != nil && !errors.Is(err, netmail.ErrHeaderNotPresent) {
+			return nil, fmt.Errorf("failed to parse %s header: %w", key, err)
+		}
+		for _, addr := range addrs {
+			a := normalizeAddress(addr.Address)
+			if !recipients[a] {
+				return nil, fmt.Errorf(
+					"%s is in the %s header but not a recipient of %s;"+
+						" remove -t from smtp.sendmail-cmd to send Bcc batches",
+					a, key, e.MessageID,
+				)
+			}
+			delete(recipients, a)
+		}
+	}
+	if len(recipients) == 0 {
+		return e.Data, nil
+	}
+	bcc := make([]string, 0, len(recipients))
+	for _, rcpt := range e.Recipients {
+		if a := normalizeAddress(rcpt); recipients[a] {
+			bcc = append(bcc, a)
+		}
+	}
+	var b bytes.Buffer
+	b.WriteString("Bcc: " + strings.Join(bcc, ",\r\n ") + "\r\n")
+	b.Write(e.Data)
+	return b.Bytes(), nil
+}
@@ -0,0 +1,271 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	gomail "github.com/wneessen/go-mail"
+	"github.com/wneessen/go-mail/smtp"
+	"go.gtmx.me/goorphans/config"
+)
+
+// Envelope is a finalized message along with its SMTP envelope.
+type Envelope struct {
+	MessageID string `json:"message_id"`
+	Subject   string `json:"subject"`
+	// Envelope sender
+	From string `json:"from"`
+	// All To, Cc, and Bcc addresses
+	Recipients []string `json:"recipients"`
+	// The rendered message. Bcc addresses are not included in the headers.
+	Data []byte `json:"-"`
+}
+
+// NewEnvelope renders a message that was finalized with [FinalizeMsg].
+func NewEnvelope(msg *gomail.Msg) (*Envelope, error) {
+	e := &Envelope{MessageID: msg.GetMessageID()}
+	if subject := msg.GetGenHeader(gomail.HeaderSubject); len(subject) > 0 {
+		e.Subject = subject[0]
+	}
+	var err error
+	if e.From, err = msg.GetSender(false); err != nil {
+		return nil, err
+	}
+	if e.Recipients, err = msg.GetRecipients(); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if _, err = msg.WriteTo(&b); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", e.MessageID, err)
+	}
+	e.Data = b.Bytes()
+	return e, nil
+}
+
+// Transport delivers envelopes.
+type Transport interface {
+	// Send delivers e and returns the server's response.
+	Send(ctx context.Context, e *Envelope) (string, error)
+	Close() error
+}
+
+// NewTransport returns the [Transport] selected by smtp.transport.
+// smtpConfig must already be validated.
+// Temporary failures are retried up to smtp.retries times and
+// if smtp.rate-limit is set, the transport is throttled.
+func NewTransport(smtpConfig *config.SMTPConfig) (Transport, error) {
+	var t Transport
+	switch smtpConfig.Transport {
+	case config.TransportDir:
+		t = &dirTransport{smtpConfig.OutgoingDir}
+	case config.TransportMbox:
+		t = &mboxTransport{smtpConfig.Mbox}
+	case config.TransportMaildir:
+		t = &maildirTransport{smtpConfig.Maildir}
+	case config.TransportSendmail:
+		cmd, err := smtpConfig.SendmailArgs()
+		if err != nil {
+			return nil, err
+		}
+		t = newSendmailTransport(cmd)
+	case config.TransportSMTP:
+		t = &smtpTransport{config: smtpConfig}
+	default:
+		return nil, fmt.Errorf("invalid transport: %q", smtpConfig.Transport)
+	}
+	if smtpConfig.Retries > 0 {
+		t = &retryingTransport{
+			Transport: t,
+			retries:   smtpConfig.Retries,
+			delay:     time.Duration(smtpConfig.RetryDelay * float64(time.Second)),
+		}
+	}
+	if smtpConfig.RateLimit > 0 {
+		t = &throttledTransport{
+			Transport: t,
+			interval:  time.Minute / time.Duration(smtpConfig.RateLimit),
+		}
+	}
+	return t, nil
+}
+
+// throttledTransport waits at least interval between messages.
+type throttledTransport struct {
+	Transport
+	interval time.Duration
+	last     time.Time
+}
+
+func (t *throttledTransport) Send(ctx context.Context, e *Envelope) (string, error) {
+	if !t.last.IsZero() {
+		if err := sleep(ctx, time.Until(t.last.Add(t.interval))); err != nil {
+			return "", err
+		}
+	}
+	t.last = time.Now()
+	return t.Transport.Send(ctx, e)
+}
+
+// retryingTransport retries temporary failures with exponential backoff.
+type retryingTransport struct {
+	Transport
+	retries int
+	// Delay before the first retry. It's doubled for each subsequent retry.
+	delay time.Duration
+}
+
+func (t *retryingTransport) Send(ctx context.Context, e *Envelope) (string, error) {
+	delay := t.delay
+	for attempt := 1; ; attempt++ {
+		response, err := t.Transport.Send(ctx, e)
+		if err == nil || attempt > t.retries || ErrorClassOf(err) != ErrTemporary {
+			return response, err
+		}
+		log.Printf(
+			"%s: %v; retrying in %s (%d/%d)",
+			e.MessageID, err, delay, attempt, t.retries,
+		)
+		if err = sleep(ctx, delay); err != nil {
+			return "", err
+		}
+		delay *= 2
+	}
+}
+
+// sleep waits for d or until ctx is canceled.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// dirTransport writes messages to a directory instead of sending them.
+type dirTransport struct {
+	dir string
+}
+
+func (t *dirTransport) Send(_ context.Context, e *Envelope) (string, error) {
+	p := path.Join(t.dir, e.MessageID+".eml")
+	if err := os.WriteFile(p, e.Data, 0o644); err != nil {
+		return "", err
+	}
+	return "written to " + p, nil
+}
+
+func (t *dirTransport) Close() error {
+	return nil
+}
+
+// smtpTransport sends messages over a single SMTP session that's opened
+// when the first message is sent.
+type smtpTransport struct {
+	config  *config.SMTPConfig
+	client  *gomail.Client
+	sclient *smtp.Client
+}
+
+func (t *smtpTransport) connect(ctx context.Context) error {
+	if t.sclient != nil {
+		return nil
+	}
+	c, err := NewClient(t.config)
+	if err != nil {
+		return err
+	}
+	sclient, err := c.DialToSMTPClientWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	t.client, t.sclient = c, sclient
+	return nil
+}
+
+// Send delivers e.
+// Recipients that are permanently rejected are logged and skipped.
+// If the session fails, the next call reconnects.
+func (t *smtpTransport) Send(ctx context.Context, e *Envelope) (string, error) {
+	response, err := t.send(ctx, e)
+	if err == nil {
+		return response, nil
+	}
+	serr := classify(err)
+	if t.sclient != nil && (isReply(err) || serr.Class == ErrRecipientRejected) {
+		_ = t.sclient.Reset()
+	} else {
+		_ = t.Close()
+	}
+	return "", serr
+}
+
+func (t *smtpTransport) send(ctx context.Context, e *Envelope) (string, error) {
+	if err := t.connect(ctx); err != nil {
+		return "", err
+	}
+	if err := t.sclient.Mail(e.From); err != nil {
+		return "", fmt.Errorf("MAIL FROM %s: %w", e.From, err)
+	}
+	var rejected []RejectedRecipient
+	for _, rcpt := range e.Recipients {
+		err := t.sclient.Rcpt(rcpt)
+		if err == nil {
+			continue
+		}
+		if classify(err).Class != ErrPermanent {
+			return "", fmt.Errorf("RCPT TO %s: %w", rcpt, err)
+		}
+		rejected = append(rejected, RejectedRecipient{rcpt, err})
+	}
+	if len(rejected) == len(e.Recipients) {
+		return "", &SendError{
+			ErrRecipientRejected,
+			fmt.Errorf("all recipients were rejected: %s", formatRejected(rejected)),
+		}
+	}
+	w, err := t.sclient.Data()
+	if err != nil {
+		return "", fmt.Errorf("DATA: %w", err)
+	}
+	if _, err = w.Write(e.Data); err != nil {
+		_ = w.Close()
+		return "", err
+	}
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+	var response string
+	if dc, ok := w.(*smtp.DataCloser); ok {
+		response = dc.ServerResponse()
+	}
+	if len(rejected) > 0 {
+		log.Printf(
+			"%s: skipped rejected recipients: %s",
+			e.MessageID, formatRejected(rejected),
+		)
+		response += "; rejected: " + formatRejected(rejected)
+	}
+	// The message was delivered, so don't return an error that would cause
+	// it to be retried.
+	if err = t.sclient.Reset(); err != nil {
+		_ = t.Close()
+	}
+	return response, nil
+}
+
+func (t *smtpTransport) Close() error {
+	if t.sclient == nil {
+		return nil
+	}
+	err := t.client.CloseWithSMTPClient(t.sclient)
+	t.client, t.sclient = nil, nil
+	return err
+}
@@ -4,6 +4,7 @@ package notifs
 import (
 	_ "embed"
 	"slices"
+	"strings"
 
 	mapset "github.com/deckarep/golang-set/v2"
 	"go.gtmx.me/goorphans/common"
@@ -13,9 +14,9 @@ import (
 var UserTemplate = templates.Templates.Lookup("notifs_user.gotmpl")
 
 type UserTemplateData struct {
-	User     string
-	Orphaned []string
-	Indirect []string
+	User     string   `json:"user"`
+	Orphaned []string `json:"orphaned"`
+	Indirect []string `json:"indirect"`
 }
 
 const UserSubjectFmt = "Orphaned packages summary for @%s"
@@ -34,6 +35,37 @@ func GetUserTemplateData(o *common.Orphans, user string) *UserTemplateData {
 	}
 }
 
+var AdoptionTemplate = templates.Templates.Lookup("notifs_adoption.gotmpl")
+
+type AdoptionTemplateData struct {
+	User     string   `json:"user"`
+	Packages []string `json:"packages"`
+}
+
+const AdoptionSubjectFmt = "Thank you for adopting %s"
+
+// GetAdoptionTemplateData groups adoptions by the new admin.
+func GetAdoptionTemplateData(adoptions []common.Adoption) []*AdoptionTemplateData {
+	byUser := map[string]*AdoptionTemplateData{}
+	var r []*AdoptionTemplateData
+	for _, a := range adoptions {
+		td, ok := byUser[a.Admin]
+		if !ok {
+			td = &AdoptionTemplateData{User: a.Admin}
+			byUser[a.Admin] = td
+			r = append(r, td)
+		}
+		td.Packages = append(td.Packages, a.Package)
+	}
+	for _, td := range r {
+		slices.Sort(td.Packages)
+	}
+	slices.SortFunc(r, func(a, b *AdoptionTemplateData) int {
+		return strings.Compare(a.User, b.User)
+	})
+	return r
+}
+
 // TODO: pagure.io/fesco/issue/3475
 // var FakeGroupAdminTemplate = templates.Templates.Lookup("notifs_fake-group-user.gotmpl")
 //
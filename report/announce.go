@@ -0,0 +1,94 @@
+package report
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/templates"
+)
+
+// AnnounceTemplate is executed with an [AnnounceData] value.
+var AnnounceTemplate = templates.Templates.Lookup("orphans_announce.gotmpl")
+
+// CategoryCount is the number of packages in a [common.Category].
+type CategoryCount struct {
+	Category common.Category
+	Count    int
+}
+
+// AnnounceData is passed to the announcement templates.
+type AnnounceData struct {
+	*Data
+	Counts []CategoryCount
+	// The earliest date after Now when more orphans become eligible for
+	// retirement and how many become eligible that day.
+	// NextRetirement is nil if no orphans are waiting.
+	NextRetirement      *time.Time
+	NextRetirementCount int
+	// The number of orphans that are eligible for retirement at Now
+	Eligible  int
+	Adoptions []common.Adoption
+	// Base URL where orphans.txt and orphans.json are published
+	BaseURL string
+	// The full report
+	Report string
+}
+
+// NewAnnounceData prepares the announcement data.
+// deadlines are the result of [common.Orphans.Deadlines].
+func NewAnnounceData(data *Data, deadlines []common.Deadline) *AnnounceData {
+	d := &AnnounceData{Data: data}
+	for _, c := range common.Categories {
+		d.Counts = append(
+			d.Counts,
+			CategoryCount{c, len(*data.Orphans.CategoryPackages(c))},
+		)
+	}
+	for _, dl := range deadlines {
+		if dl.Eligible(data.Now) {
+			d.Eligible++
+			continue
+		}
+		day := dl.RetireAt.UTC().Truncate(24 * time.Hour)
+		switch {
+		case d.NextRetirement == nil || day.Before(*d.NextRetirement):
+			d.NextRetirement = &day
+			d.NextRetirementCount = 1
+		case day.Equal(*d.NextRetirement):
+			d.NextRetirementCount++
+		}
+	}
+	return d
+}
+
+// URL returns the URL of name under BaseURL.
+func (d *AnnounceData) URL(name string) string {
+	u, err := url.JoinPath(d.BaseURL, name)
+	if err != nil {
+		return name
+	}
+	return u
+}
+
+// Date returns Now as a YYYY-MM-DD date.
+func (d *AnnounceData) Date() string {
+	return d.Now.UTC().Format(time.DateOnly)
+}
+
+// RenderSubject executes the subject template text with data.
+// Runs of whitespace, including newlines, are collapsed into a single space.
+func RenderSubject(text string, data *AnnounceData) (string, error) {
+	tmpl, err := template.New("subject").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err = tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(b.String()), " "), nil
+}
@@ -0,0 +1,192 @@
+// Package report renders the human-readable orphans report from orphans.json
+package report
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.gtmx.me/goorphans/common"
+	"go.gtmx.me/goorphans/templates"
+)
+
+var (
+	TXTTemplate      = templates.Templates.Lookup("orphans_report.gotmpl")
+	MarkdownTemplate = templates.Templates.Lookup("orphans_report.md.gotmpl")
+	// Executed with a generate.EPELOrphans value
+	EPELTemplate = templates.Templates.Lookup("epel_orphans.gotmpl")
+)
+
+// ParseTemplate parses a custom report template from the file at name.
+// The named templates defined by the builtin templates (e.g.,
+// "orphans_report_node") can be used from it.
+func ParseTemplate(name string) (*template.Template, error) {
+	t, err := templates.Templates.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = t.ParseFiles(name); err != nil {
+		return nil, err
+	}
+	return t.Lookup(filepath.Base(name)), nil
+}
+
+// Node is a package in a dependency tree.
+type Node struct {
+	Package     string
+	Maintainers []string
+	Requires    []common.Requirement
+	// 1 for packages that depend on the orphan directly
+	Depth    int
+	Children []*Node
+}
+
+// Indent returns a tab for each level of Depth.
+func (n *Node) Indent() string {
+	return strings.Repeat("\t", n.Depth)
+}
+
+// ListIndent returns the indentation for a nested Markdown list item at
+// Depth.
+func (n *Node) ListIndent() string {
+	return strings.Repeat("  ", n.Depth-1)
+}
+
+// Tree is the dependency tree of a single orphan.
+type Tree struct {
+	Orphan string
+	// The number of dependents stated in orphans.txt or the number of nodes
+	// in the tree
+	Count      int
+	Dependents []*Node
+}
+
+// Data is passed to the report templates.
+type Data struct {
+	Orphans *common.Orphans
+	// Dependency trees of the orphans that break dependencies, sorted by
+	// orphan name.
+	// This is empty when the dependency information is not available.
+	Trees []*Tree
+	// Package -> users and @groups that maintain it
+	PackageMaintainers map[string][]string
+	// The time that status changes are compared to.
+	// This is when the report was started so the output doesn't depend on
+	// when it's rendered.
+	Now time.Time
+}
+
+// NewData prepares the template data for o.
+// tree is optional.
+// The maintainers of orphans are taken from [common.Orphans.AffectedPeople]
+// and the maintainers of dependents are taken from tree.
+func NewData(o *common.Orphans, tree *common.DepTree) *Data {
+	d := &Data{Orphans: o, PackageMaintainers: map[string][]string{}}
+	switch {
+	case o.StartedAt != nil:
+		d.Now = *o.StartedAt
+	case o.FinishedAt != nil:
+		d.Now = *o.FinishedAt
+	default:
+		d.Now = time.Now()
+	}
+	for person, pkgs := range o.AffectedPeople {
+		for _, pkg := range pkgs {
+			d.PackageMaintainers[pkg] = append(d.PackageMaintainers[pkg], person)
+		}
+	}
+	for _, people := range d.PackageMaintainers {
+		slices.Sort(people)
+	}
+	if tree == nil {
+		return d
+	}
+	for _, orphan := range slices.Sorted(maps.Keys(tree.Orphans)) {
+		deps := tree.Orphans[orphan]
+		for _, dep := range deps.Dependents {
+			d.PackageMaintainers[dep.Package] = dep.Maintainers
+		}
+		count := deps.Count
+		if count == 0 {
+			count = len(deps.Dependents)
+		}
+		d.Trees = append(d.Trees, &Tree{
+			Orphan:     orphan,
+			Count:      count,
+			Dependents: children(deps.Dependents, []string{orphan}),
+		})
+	}
+	return d
+}
+
+// children returns the dependents whose parent is at the end of path.
+func children(deps []*common.Dependent, path []string) []*Node {
+	var r []*Node
+	for _, dep := range deps {
+		if len(dep.Path) != len(path)+1 || !slices.Equal(dep.Path[:len(path)], path) {
+			continue
+		}
+		r = append(r, &Node{
+			Package:     dep.Package,
+			Maintainers: dep.Maintainers,
+			Requires:    dep.Requires,
+			Depth:       len(path),
+			Children:    children(deps, dep.Path),
+		})
+	}
+	return r
+}
+
+// Maintainers returns the comma separated maintainers of pkg.
+func (d *Data) Maintainers(pkg string) string {
+	return strings.Join(d.PackageMaintainers[pkg], ", ")
+}
+
+// StatusChange returns the date that pkg was orphaned and how long ago that
+// was, e.g., "2025-01-01 (2 weeks ago)".
+func (d *Data) StatusChange(pkg string) string {
+	t, ok := d.Orphans.StatusChange[pkg]
+	if !ok {
+		return "unknown"
+	}
+	weeks := int(d.Now.Sub(t) / common.Weeks(1))
+	ago := fmt.Sprintf("%d weeks ago", weeks)
+	if weeks == 1 {
+		ago = "1 week ago"
+	}
+	return fmt.Sprintf("%s (%s)", t.Format(time.DateOnly), ago)
+}
+
+// People returns the sorted keys of [common.Orphans.AllAffectedPeople].
+func (d *Data) People() []string {
+	return slices.Sorted(maps.Keys(d.Orphans.AllAffectedPeople))
+}
+
+// AffectedBy returns the comma separated orphans that affect person.
+func (d *Data) AffectedBy(person string) string {
+	return strings.Join(d.Orphans.AllAffectedPeople[person], ", ")
+}
+
+// RetirementWeeks returns [common.RetirementWeeks].
+func (d *Data) RetirementWeeks() int {
+	return common.RetirementWeeks
+}
+
+// FormatTime formats t for the report.
+// It returns "unknown" if t is nil.
+func (d *Data) FormatTime(t *time.Time) string {
+	if t == nil {
+		return "unknown"
+	}
+	return t.UTC().Format("2006-01-02 15:04:05 MST")
+}
+
+// Render executes tmpl with data and writes the output to w.
+func Render(w io.Writer, tmpl *template.Template, data *Data) error {
+	return tmpl.Execute(w, data)
+}
@@ -2,10 +2,17 @@ package templates
 
 import (
 	"embed"
+	"strings"
 	"text/template"
 )
 
 //go:embed *.gotmpl
 var templateFS embed.FS
 
-var Templates = template.Must(template.ParseFS(templateFS, "*.gotmpl"))
+var funcs = template.FuncMap{
+	"join": strings.Join,
+}
+
+var Templates = template.Must(
+	template.New("").Funcs(funcs).ParseFS(templateFS, "*.gotmpl"),
+)